@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Sliding window aggregates for groups of IPs (an IPv4 /24, an ASN), so a ring
+// rotating addresses inside one subnet or hosting provider still shows up.
+//
+// The window is split into fixed buckets. Each bucket is a hash with txn_count
+// and total_amount plus a HyperLogLog of user IDs:
+//
+//	agg:{<scope>:<id>}:<bucket start unix>    txn_count, total_amount
+//	agg:{<scope>:<id>}:<bucket start unix>:u  PFADD user_id
+//
+// The {...} hash tag keeps every key of one aggregate in the same cluster
+// slot, so all buckets are updated and read in one pipeline and distinct users
+// across the window is a single multi-key PFCOUNT.
+//
+// Buckets are chosen by event time (see event_time.go); their TTL only
+// garbage collects them.
+const (
+	defaultAggWindow = time.Hour
+	defaultAggBucket = 5 * time.Minute
+)
+
+type aggregateConfig struct {
+	window time.Duration
+	bucket time.Duration
+}
+
+var aggConfig = loadAggregateConfig()
+
+func loadAggregateConfig() aggregateConfig {
+	cfg := aggregateConfig{window: defaultAggWindow, bucket: defaultAggBucket}
+	if env := os.Getenv("AGG_WINDOW_MINUTES"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			cfg.window = time.Duration(parsed) * time.Minute
+		}
+	}
+	if env := os.Getenv("AGG_BUCKET_MINUTES"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			cfg.bucket = time.Duration(parsed) * time.Minute
+		}
+	}
+	if cfg.bucket > cfg.window {
+		log.Printf("AGG_BUCKET_MINUTES is larger than the window, using one bucket of %v", cfg.window)
+		cfg.bucket = cfg.window
+	}
+	return cfg
+}
+
+// updateAggregate records one transaction that happened at `at` (event time)
+// against the aggregate scope/id and returns the totals over the window ending
+// at that time, this transaction included.
+func updateAggregate(client *redis.ClusterClient, ctx context.Context, scope string, id string, userID string, amount float64, at time.Time) (*AggregateSignals, error) {
+	base := fmt.Sprintf("agg:{%s:%s}", scope, id)
+	bucketCount := int((aggConfig.window + aggConfig.bucket - 1) / aggConfig.bucket)
+	current := at.Truncate(aggConfig.bucket)
+	ttl := aggConfig.window + aggConfig.bucket
+
+	bucketKey := func(start time.Time) string {
+		return base + ":" + strconv.FormatInt(start.Unix(), 10)
+	}
+
+	var counts []*redis.MapStringStringCmd
+	var users *redis.IntCmd
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		key := bucketKey(current)
+		pipe.HIncrBy(ctx, key, "txn_count", 1)
+		pipe.HIncrByFloat(ctx, key, "total_amount", amount)
+		pipe.Expire(ctx, key, ttl)
+		pipe.PFAdd(ctx, key+":u", userID)
+		pipe.Expire(ctx, key+":u", ttl)
+
+		userKeys := make([]string, 0, bucketCount)
+		for i := 0; i < bucketCount; i++ {
+			key := bucketKey(current.Add(-time.Duration(i) * aggConfig.bucket))
+			counts = append(counts, pipe.HGetAll(ctx, key))
+			userKeys = append(userKeys, key+":u")
+		}
+		users = pipe.PFCount(ctx, userKeys...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aggregate %s pipeline failed: %w", base, err)
+	}
+
+	agg := &AggregateSignals{
+		Key:           scope + ":" + id,
+		DistinctUsers: int(users.Val()),
+		WindowSeconds: int64(aggConfig.window / time.Second),
+	}
+	for _, cmd := range counts {
+		bucket := cmd.Val()
+		if n, err := strconv.Atoi(bucket["txn_count"]); err == nil {
+			agg.TxnCount += n
+		}
+		if total, err := strconv.ParseFloat(bucket["total_amount"], 64); err == nil {
+			agg.TotalAmount += total
+		}
+	}
+	return agg, nil
+}
+
+// windowAmounts keeps the amounts of selected transactions in a sorted set
+// scored by event time, for the detectors that need each amount rather than
+// bucket totals (card_testing.go, structuring.go):
+//
+//	<key>    ZADD <event time unix millis> "<txn id>|<amount>"
+//
+// With record set the transaction is added first. It returns the amounts
+// within window before `at` (event time). Entries older than two windows are
+// trimmed, so events up to one window late still see theirs, and only the
+// newest maxEntries are kept.
+func windowAmounts(client *redis.ClusterClient, ctx context.Context, key string, record bool, txnID string, amount float64, at time.Time, window time.Duration, maxEntries int) ([]float64, error) {
+	end := at.UnixMilli()
+	start := at.Add(-window).UnixMilli()
+
+	var entries *redis.ZSliceCmd
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if record {
+			member := txnID + "|" + strconv.FormatFloat(amount, 'f', -1, 64)
+			pipe.ZAdd(ctx, key, redis.Z{Score: float64(end), Member: member})
+		}
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(at.Add(-2*window).UnixMilli(), 10))
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-maxEntries-1))
+		entries = pipe.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+			Min: strconv.FormatInt(start, 10),
+			Max: strconv.FormatInt(end, 10),
+		})
+		pipe.Expire(ctx, key, 2*window)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var amounts []float64
+	for _, entry := range entries.Val() {
+		member, _ := entry.Member.(string)
+		sep := strings.LastIndexByte(member, '|')
+		if sep < 0 {
+			continue
+		}
+		if value, err := strconv.ParseFloat(member[sep+1:], 64); err == nil {
+			amounts = append(amounts, value)
+		}
+	}
+	return amounts, nil
+}
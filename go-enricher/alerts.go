@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+
+	pb "fraud-enricher/pb"
+)
+
+const (
+	alertsKafkaTopic     = "fraud_alerts"
+	amlAlertsKafkaTopic  = "aml_alerts"
+	defaultAlertCooldown = 10 * time.Minute
+)
+
+// alertPublisher turns rule hits into Alert messages on the alerts topic.
+// The same IP/rule pair alerts at most once per cooldown window; the cooldown
+// is tracked in Redis so it holds across enricher replicas. AML alerts go to
+// KAFKA_AML_ALERTS_TOPIC instead, keyed and cooled down per user, since an
+// AML case is about the customer rather than the network. The key holds the
+// cooldown's end by the enricher clock (clock.go); its Redis TTL only cleans
+// it up.
+type alertPublisher struct {
+	producer *kafka.Producer
+	client   *redis.ClusterClient
+	topic    string
+	amlTopic string
+	cooldown time.Duration
+}
+
+func newAlertPublisher(producer *kafka.Producer, client *redis.ClusterClient) *alertPublisher {
+	topic := os.Getenv("KAFKA_ALERTS_TOPIC")
+	if topic == "" {
+		topic = alertsKafkaTopic
+	}
+	amlTopic := os.Getenv("KAFKA_AML_ALERTS_TOPIC")
+	if amlTopic == "" {
+		amlTopic = amlAlertsKafkaTopic
+	}
+	cooldown := defaultAlertCooldown
+	if env := os.Getenv("ALERT_COOLDOWN_SECONDS"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed >= 0 {
+			cooldown = time.Duration(parsed) * time.Second
+		}
+	}
+	return &alertPublisher{producer: producer, client: client, topic: topic, amlTopic: amlTopic, cooldown: cooldown}
+}
+
+// acquireCooldown sets KEYS[1] to the new cooldown end (ARGV[2]) unless the
+// current one is still after now (ARGV[1]). ARGV[3] is the TTL in millis.
+var acquireCooldown = redis.NewScript(`
+local until = tonumber(redis.call('GET', KEYS[1]))
+if until and until > tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+func alertID(transactionID, ruleID string) string {
+	return contentHash([]byte(transactionID + "|" + ruleID))[:32]
+}
+
+// publish sends an alert for the hit unless the IP/rule pair (user/rule pair
+// for AML) is cooling down.
+func (a *alertPublisher) publish(ctx context.Context, enriched *pb.EnrichedTransaction, hit *pb.RuleHit, features map[string]interface{}) (string, error) {
+	txn := enriched.Transaction
+	id := alertID(txn.TransactionId, hit.RuleId)
+
+	alertType, topic, key := alertTypeFraud, &a.topic, txn.IpAddress
+	if hit.AlertType == alertTypeAML {
+		alertType, topic, key = alertTypeAML, &a.amlTopic, txn.UserId
+	}
+
+	cooldownKey := "alert:cooldown:" + hit.RuleId + ":" + key
+	if a.cooldown > 0 {
+		now := clock.Now()
+		acquired, err := acquireCooldown.Run(ctx, a.client, []string{cooldownKey},
+			now.UnixMilli(), now.Add(a.cooldown).UnixMilli(), a.cooldown.Milliseconds()).Int()
+		if err != nil {
+			return "REDIS_ISSUE", fmt.Errorf("cooldown check failed: %w", err)
+		}
+		if acquired == 0 {
+			return "SUPPRESSED", nil
+		}
+	}
+
+	alert := &pb.Alert{
+		AlertId:             id,
+		TransactionId:       txn.TransactionId,
+		UserId:              txn.UserId,
+		IpAddress:           txn.IpAddress,
+		RuleId:              hit.RuleId,
+		Severity:            hit.Severity,
+		ReasonCodes:         []string{hit.ReasonCode},
+		Action:              hit.Action,
+		RuleSetVersion:      enriched.RuleSetVersion,
+		CreatedAt:           clock.Now().UnixMilli(),
+		NumericFeatures:     make(map[string]float64),
+		CategoricalFeatures: make(map[string]string),
+		Conditions:          hit.Conditions,
+		AlertType:           alertType,
+	}
+	for name, value := range features {
+		switch v := value.(type) {
+		case float64:
+			alert.NumericFeatures[name] = v
+		case bool:
+			if v {
+				alert.NumericFeatures[name] = 1
+			} else {
+				alert.NumericFeatures[name] = 0
+			}
+		case string:
+			alert.CategoricalFeatures[name] = v
+		}
+	}
+
+	value, err := proto.Marshal(alert)
+	if err != nil {
+		return "MARSHAL_ISSUE", err
+	}
+	err = a.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{
+			Topic:     topic,
+			Partition: kafka.PartitionAny,
+		},
+		Key:   []byte(key),
+		Value: value,
+	}, nil)
+	if err != nil {
+		// Release the cooldown so the next matching transaction can retry
+		if a.cooldown > 0 {
+			a.client.Del(ctx, cooldownKey)
+		}
+		return "KAFKA_ISSUE", err
+	}
+	return "PUBLISHED", nil
+}
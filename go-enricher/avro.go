@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/linkedin/goavro/v2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// avroEncoder writes Avro binary using a schema derived from the proto descriptor,
+// so the Avro output follows fraud.proto without a hand maintained .avsc.
+// The schema's Rabin fingerprint is sent in the "avro-fingerprint" header.
+type avroEncoder struct {
+	codec   *goavro.Codec
+	headers []kafka.Header
+}
+
+func newAvroEncoder(desc protoreflect.MessageDescriptor) (*avroEncoder, error) {
+	schema, err := json.Marshal(avroSchema(desc, make(map[protoreflect.FullName]bool)))
+	if err != nil {
+		return nil, err
+	}
+	codec, err := goavro.NewCodec(string(schema))
+	if err != nil {
+		return nil, fmt.Errorf("build avro schema for %s: %w", desc.FullName(), err)
+	}
+	log.Printf("Avro schema for %s (fingerprint %016x): %s", desc.FullName(), codec.Rabin, codec.CanonicalSchema())
+
+	fingerprint := kafka.Header{Key: "avro-fingerprint", Value: []byte(fmt.Sprintf("%016x", codec.Rabin))}
+	return &avroEncoder{
+		codec:   codec,
+		headers: encodingHeaders("avro/binary", desc, fingerprint),
+	}, nil
+}
+
+func (e *avroEncoder) Encode(msg proto.Message) ([]byte, error) {
+	return e.codec.BinaryFromNative(nil, avroRecord(msg.ProtoReflect()))
+}
+
+func (e *avroEncoder) Headers() []kafka.Header {
+	return e.headers
+}
+
+// avroSchema maps a message descriptor to an Avro record schema. Named types
+// (records and enums) are defined once and referenced by name afterwards.
+func avroSchema(desc protoreflect.MessageDescriptor, defined map[protoreflect.FullName]bool) interface{} {
+	if defined[desc.FullName()] {
+		return string(desc.FullName())
+	}
+	defined[desc.FullName()] = true
+
+	var fields []map[string]interface{}
+	for i := 0; i < desc.Fields().Len(); i++ {
+		fd := desc.Fields().Get(i)
+		field := map[string]interface{}{"name": string(fd.Name())}
+
+		switch {
+		case fd.IsMap():
+			field["type"] = map[string]interface{}{"type": "map", "values": avroFieldType(fd.MapValue(), defined)}
+			field["default"] = map[string]interface{}{}
+		case fd.IsList():
+			field["type"] = map[string]interface{}{"type": "array", "items": avroFieldType(fd, defined)}
+			field["default"] = []interface{}{}
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			field["type"] = []interface{}{"null", avroFieldType(fd, defined)}
+			field["default"] = nil
+		default:
+			field["type"] = avroFieldType(fd, defined)
+			field["default"] = avroDefault(fd)
+		}
+		fields = append(fields, field)
+	}
+
+	return map[string]interface{}{
+		"type":   "record",
+		"name":   string(desc.FullName()),
+		"fields": fields,
+	}
+}
+
+func avroFieldType(fd protoreflect.FieldDescriptor, defined map[protoreflect.FullName]bool) interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return "boolean"
+	case protoreflect.StringKind:
+		return "string"
+	case protoreflect.BytesKind:
+		return "bytes"
+	case protoreflect.FloatKind:
+		return "float"
+	case protoreflect.DoubleKind:
+		return "double"
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "int"
+	case protoreflect.EnumKind:
+		enum := fd.Enum()
+		if defined[enum.FullName()] {
+			return string(enum.FullName())
+		}
+		defined[enum.FullName()] = true
+		symbols := make([]string, enum.Values().Len())
+		for i := range symbols {
+			symbols[i] = string(enum.Values().Get(i).Name())
+		}
+		return map[string]interface{}{
+			"type":    "enum",
+			"name":    string(enum.FullName()),
+			"symbols": symbols,
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return avroSchema(fd.Message(), defined)
+	}
+	// int64, uint32, uint64 and the fixed/zigzag variants
+	return "long"
+}
+
+func avroDefault(fd protoreflect.FieldDescriptor) interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return false
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return ""
+	case protoreflect.EnumKind:
+		return string(fd.Enum().Values().Get(0).Name())
+	}
+	return 0
+}
+
+// avroRecord converts a message into the goavro native form of avroSchema
+func avroRecord(m protoreflect.Message) map[string]interface{} {
+	record := make(map[string]interface{})
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		value := m.Get(fd)
+
+		switch {
+		case fd.IsMap():
+			entries := make(map[string]interface{})
+			value.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				entries[mapKeyString(k)] = avroValue(fd.MapValue(), v)
+				return true
+			})
+			record[string(fd.Name())] = entries
+		case fd.IsList():
+			list := value.List()
+			items := make([]interface{}, list.Len())
+			for j := 0; j < list.Len(); j++ {
+				items[j] = avroValue(fd, list.Get(j))
+			}
+			record[string(fd.Name())] = items
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			if !m.Has(fd) {
+				record[string(fd.Name())] = nil
+			} else {
+				record[string(fd.Name())] = goavro.Union(string(fd.Message().FullName()), avroRecord(value.Message()))
+			}
+		default:
+			record[string(fd.Name())] = avroValue(fd, value)
+		}
+	}
+	return record
+}
+
+func avroValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool()
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.BytesKind:
+		return v.Bytes()
+	case protoreflect.FloatKind:
+		return float32(v.Float())
+	case protoreflect.DoubleKind:
+		return v.Float()
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return int32(v.Int())
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return v.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return int64(v.Uint())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return string(fd.Enum().Values().Get(0).Name())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return avroRecord(v.Message())
+	}
+	return nil
+}
+
+func mapKeyString(k protoreflect.MapKey) string {
+	switch v := k.Interface().(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(k.Interface())
+}
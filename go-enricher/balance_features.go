@@ -0,0 +1,26 @@
+package main
+
+import (
+	"math"
+
+	pb "fraud-enricher/pb"
+)
+
+// Balances are in currency units; differences under a cent are rounding noise
+const balanceEpsilon = 0.01
+
+// balanceSignals derives the PaySim-style balance consistency features. In a
+// consistent ledger both error terms are 0: the origin loses exactly the
+// amount and the destination gains it. Fraudulent TRANSFER / CASH_OUT in this
+// dataset typically empties the origin while the destination balance doesn't
+// move.
+func balanceSignals(txn *pb.TransactionRequest) *pb.BalanceSignals {
+	amount := txn.Amount
+	return &pb.BalanceSignals{
+		ErrorBalanceOrig:     txn.NewBalanceOrig + amount - txn.OldBalanceOrig,
+		ErrorBalanceDest:     txn.OldBalanceDest + amount - txn.NewBalanceDest,
+		OrigDrained:          txn.OldBalanceOrig > balanceEpsilon && math.Abs(txn.NewBalanceOrig) < balanceEpsilon,
+		DestUnchanged:        amount > balanceEpsilon && math.Abs(txn.NewBalanceDest-txn.OldBalanceDest) < balanceEpsilon,
+		AmountExceedsBalance: amount > txn.OldBalanceOrig+balanceEpsilon,
+	}
+}
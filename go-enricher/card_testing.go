@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	pb "fraud-enricher/pb"
+)
+
+// Card testing: a stolen card is first checked with a burst of tiny
+// transactions and, once one goes through, used for a much larger amount.
+// Small transactions (amount <= CARD_TESTING_MAX_AMOUNT) are recorded per IP,
+// per subnet and per user in ct:{<scope>:<id>}, a sorted set scored by event
+// time (windowAmounts in aggregates.go).
+//
+// At least CARD_TESTING_MIN_BURST of them within CARD_TESTING_WINDOW_SECONDS
+// of event time is a burst. A transaction above the ceiling that follows a
+// burst and is at least CARD_TESTING_ESCALATION_RATIO times the largest small
+// amount is an escalation. The rules turn both into alerts.
+//
+// Only the newest cardTestingMaxEntries are kept per key; the counts saturate
+// there, far above any useful burst size.
+const (
+	defaultCardTestingWindow = 10 * time.Minute
+	defaultCardTestingMax    = 5.0
+	defaultCardTestingBurst  = 5
+	defaultCardTestingRatio  = 20.0
+
+	cardTestingMaxEntries = 500
+)
+
+type cardTestingConfig struct {
+	window          time.Duration
+	maxAmount       float64
+	minBurst        int
+	escalationRatio float64
+}
+
+var cardTestingCfg = loadCardTestingConfig()
+
+func loadCardTestingConfig() cardTestingConfig {
+	cfg := cardTestingConfig{
+		window:          defaultCardTestingWindow,
+		maxAmount:       defaultCardTestingMax,
+		minBurst:        defaultCardTestingBurst,
+		escalationRatio: defaultCardTestingRatio,
+	}
+	if env := os.Getenv("CARD_TESTING_WINDOW_SECONDS"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			cfg.window = time.Duration(parsed) * time.Second
+		}
+	}
+	if env := os.Getenv("CARD_TESTING_MAX_AMOUNT"); env != "" {
+		if parsed, err := strconv.ParseFloat(env, 64); err == nil && parsed > 0 {
+			cfg.maxAmount = parsed
+		}
+	}
+	if env := os.Getenv("CARD_TESTING_MIN_BURST"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			cfg.minBurst = parsed
+		}
+	}
+	if env := os.Getenv("CARD_TESTING_ESCALATION_RATIO"); env != "" {
+		if parsed, err := strconv.ParseFloat(env, 64); err == nil && parsed > 1 {
+			cfg.escalationRatio = parsed
+		}
+	}
+	cardTestingMetrics.Set("window_seconds", intVar(int64(cfg.window/time.Second)))
+	cardTestingMetrics.Set("min_burst", intVar(int64(cfg.minBurst)))
+	return cfg
+}
+
+// updateCardTesting records the transaction against scope/id if it is small
+// and returns the card testing signals over the window ending at `at` (event
+// time).
+func updateCardTesting(client *redis.ClusterClient, ctx context.Context, scope string, id string, txnID string, amount float64, at time.Time) (*pb.CardTestingSignals, error) {
+	key := fmt.Sprintf("ct:{%s:%s}", scope, id)
+	small := amount <= cardTestingCfg.maxAmount
+	amounts, err := windowAmounts(client, ctx, key, small, txnID, amount, at, cardTestingCfg.window, cardTestingMaxEntries)
+	if err != nil {
+		cardTestingMetrics.Add("state_errors", 1)
+		return nil, fmt.Errorf("card testing %s pipeline failed: %w", key, err)
+	}
+
+	signals := &pb.CardTestingSignals{WindowSeconds: int64(cardTestingCfg.window / time.Second)}
+	for _, value := range amounts {
+		signals.SmallTxnCount++
+		signals.SmallTxnTotal += value
+		if value > signals.MaxSmallAmount {
+			signals.MaxSmallAmount = value
+		}
+	}
+
+	signals.Burst = signals.SmallTxnCount >= int64(cardTestingCfg.minBurst)
+	if signals.MaxSmallAmount > 0 {
+		signals.EscalationRatio = amount / signals.MaxSmallAmount
+	}
+	// Only a large transaction escalates, so the burst is entirely before it
+	signals.Escalation = !small && signals.Burst && signals.EscalationRatio >= cardTestingCfg.escalationRatio
+
+	if signals.Burst && small {
+		cardTestingMetrics.Add(scope+"_burst_txns", 1)
+	}
+	if signals.Escalation {
+		cardTestingMetrics.Add(scope+"_escalations", 1)
+	}
+	return signals, nil
+}
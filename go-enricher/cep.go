@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
+
+	pb "fraud-enricher/pb"
+)
+
+// Complex event processing: declarative sequence patterns matched across a
+// user's (or any other key's) transactions within a time bound, e.g. a
+// TRANSFER that drains the origin followed by a CASH_OUT of the same amount.
+// Patterns live in CEP_PATTERNS_FILE (see patterns.yaml) and are re-read
+// every CEP_RELOAD_SECONDS.
+//
+// The key ties the steps together: every step's key feature has to have the
+// same value. It defaults to the pattern's key, and a step can name its own,
+// so a sequence can follow money from one account to another (a TRANSFER's
+// transaction.dest_account, then a CASH_OUT by that account's
+// transaction.user_id).
+//
+// Every step is an expression in the rule syntax. Values a step captures are
+// available to the later steps as captured.<name>. Steps match in order,
+// skipping unrelated transactions in between, and the whole sequence has to
+// fit in `within` of event time. A transaction can advance several partial
+// matches and start a new one.
+//
+// Partial matches are kept in Redis per pattern and key value, so they
+// survive restarts and are shared across replicas:
+//
+//	cep:{<pattern id>:<key value>}    JSON list of partial matches
+//
+// updated under WATCH so concurrent updates of one key retry instead of
+// losing a step (cepStore). A completed pattern becomes a RuleHit (rule id = pattern id)
+// and goes through alerting and the decision stage like any rule hit; shadow
+// patterns are recorded as shadow_rule_hits only.
+const (
+	defaultCEPPatternsFile = "patterns.yaml"
+	defaultCEPReload       = 30 * time.Second
+
+	// Partial matches kept per pattern and key; the oldest go first
+	cepMaxPartials = 16
+	cepMaxRetries  = 3
+)
+
+type cepStep struct {
+	Name       string            `yaml:"name"`
+	Key        string            `yaml:"key"` // defaults to the pattern's key
+	Expression string            `yaml:"expression"`
+	Capture    map[string]string `yaml:"capture,omitempty"` // name -> feature
+
+	compiled exprNode
+}
+
+type cepPattern struct {
+	ID          string     `yaml:"id"`
+	Description string     `yaml:"description"`
+	Key         string     `yaml:"key"` // feature the sequence is tracked per, unless a step has its own
+	Within      string     `yaml:"within"`
+	Severity    string     `yaml:"severity"`
+	ReasonCode  string     `yaml:"reason_code"`
+	Action      string     `yaml:"action"`
+	State       string     `yaml:"state"`
+	Steps       []*cepStep `yaml:"steps"`
+
+	within  time.Duration
+	version string // hash of key, within and steps, stamped on partial matches
+}
+
+type cepPatternSet struct {
+	Version  string        `yaml:"version"`
+	Patterns []*cepPattern `yaml:"patterns"`
+}
+
+// cepPartial is a sequence matched up to (not including) step Next
+type cepPartial struct {
+	Version   string                 `json:"version"` // cepPattern.version it was matched against
+	Next      int                    `json:"next"`
+	StartedAt int64                  `json:"started_at"` // event time of the first step, unix millis
+	LastAt    int64                  `json:"last_at"`    // event time of the latest step
+	Captured  map[string]interface{} `json:"captured"`
+	Txns      []string               `json:"txns"` // transaction id per matched step
+}
+
+func parseCEPPatterns(data []byte) (*cepPatternSet, error) {
+	set := &cepPatternSet{}
+	if err := yaml.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("parse patterns: %w", err)
+	}
+	if set.Version == "" {
+		set.Version = "sha256:" + contentHash(data)[:12]
+	}
+
+	seen := make(map[string]bool)
+	for i, pattern := range set.Patterns {
+		if pattern.ID == "" {
+			return nil, fmt.Errorf("pattern #%d has no id", i)
+		}
+		if seen[pattern.ID] {
+			return nil, fmt.Errorf("duplicate pattern id %q", pattern.ID)
+		}
+		seen[pattern.ID] = true
+
+		if pattern.Key == "" {
+			pattern.Key = "transaction.user_id"
+		}
+		within, err := time.ParseDuration(pattern.Within)
+		if err != nil || within <= 0 {
+			return nil, fmt.Errorf("pattern %s: within must be a positive duration such as 30m, got %q", pattern.ID, pattern.Within)
+		}
+		pattern.within = within
+
+		if pattern.Severity == "" {
+			pattern.Severity = "medium"
+		}
+		if !validSeverities[pattern.Severity] {
+			return nil, fmt.Errorf("pattern %s: invalid severity %q", pattern.ID, pattern.Severity)
+		}
+		if pattern.Action == "" {
+			pattern.Action = "alert"
+		}
+		if !validActions[pattern.Action] {
+			return nil, fmt.Errorf("pattern %s: invalid action %q", pattern.ID, pattern.Action)
+		}
+		if pattern.ReasonCode == "" {
+			pattern.ReasonCode = pattern.ID
+		}
+		if pattern.State == "" {
+			pattern.State = ruleStateActive
+		}
+		if !validStates[pattern.State] {
+			return nil, fmt.Errorf("pattern %s: invalid state %q", pattern.ID, pattern.State)
+		}
+
+		if len(pattern.Steps) < 2 {
+			return nil, fmt.Errorf("pattern %s: a sequence needs at least two steps (use a rule for one)", pattern.ID)
+		}
+		for j, step := range pattern.Steps {
+			if step.Name == "" {
+				step.Name = strconv.Itoa(j + 1)
+			}
+			if step.Key == "" {
+				step.Key = pattern.Key
+			}
+			compiled, err := compileExpr(step.Expression)
+			if err != nil {
+				return nil, fmt.Errorf("pattern %s step %s: %w", pattern.ID, step.Name, err)
+			}
+			step.compiled = compiled
+		}
+		definition, err := json.Marshal(struct {
+			Key    string
+			Within time.Duration
+			Steps  []*cepStep
+		}{pattern.Key, pattern.within, pattern.Steps})
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %w", pattern.ID, err)
+		}
+		pattern.version = contentHash(definition)[:12]
+	}
+	return set, nil
+}
+
+// cepStore holds the partial matches per Redis key. update passes the stored
+// partials to fn and writes back what it keeps when it reports a change,
+// running fn again if the key changed concurrently.
+type cepStore interface {
+	update(ctx context.Context, key string, ttl time.Duration, fn func(partials []*cepPartial) (kept []*cepPartial, changed bool)) error
+}
+
+type redisCEPStore struct {
+	client *redis.ClusterClient
+}
+
+func (s redisCEPStore) update(ctx context.Context, key string, ttl time.Duration, fn func([]*cepPartial) ([]*cepPartial, bool)) error {
+	txf := func(tx *redis.Tx) error {
+		var partials []*cepPartial
+		value, err := tx.Get(ctx, key).Result()
+		switch {
+		case err == redis.Nil:
+		case err != nil:
+			return err
+		default:
+			if err := json.Unmarshal([]byte(value), &partials); err != nil {
+				// Unreadable state only loses the sequences in flight
+				log.Printf("Discarding unreadable pattern state %s: %v", key, err)
+				partials = nil
+			}
+		}
+
+		kept, changed := fn(partials)
+		if !changed {
+			return nil
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if len(kept) == 0 {
+				pipe.Del(ctx, key)
+				return nil
+			}
+			data, err := json.Marshal(kept)
+			if err != nil {
+				return err
+			}
+			// The TTL only cleans up; expiry is checked in event time
+			pipe.Set(ctx, key, data, ttl)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < cepMaxRetries; i++ {
+		err := s.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("%s changed concurrently %d times", key, cepMaxRetries)
+}
+
+type cepEngine struct {
+	current atomic.Pointer[cepPatternSet]
+	path    string
+	version fileVersion
+	store   cepStore
+}
+
+func newCEPEngine(client *redis.ClusterClient) (*cepEngine, error) {
+	e := &cepEngine{path: os.Getenv("CEP_PATTERNS_FILE"), store: redisCEPStore{client}}
+	if e.path == "" {
+		e.path = defaultCEPPatternsFile
+	}
+	if _, err := e.reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *cepEngine) Patterns() *cepPatternSet {
+	return e.current.Load()
+}
+
+// Process feeds one enriched transaction that happened at `at` to every
+// enabled pattern and returns the patterns it completed. A pattern whose
+// state can't be read or written is logged and skipped.
+func (e *cepEngine) Process(ctx context.Context, txnID string, features map[string]interface{}, at time.Time) (hits []*pb.RuleHit, shadowHits []*pb.RuleHit) {
+	for _, pattern := range e.Patterns().Patterns {
+		if pattern.State == ruleStateDisabled {
+			continue
+		}
+		for _, key := range pattern.keys(features) {
+			completed, err := e.advance(ctx, pattern, key, txnID, features, at)
+			if err != nil {
+				cepMetrics.Add("state_errors", 1)
+				log.Printf("Pattern %s state update failed for %s: %v", pattern.ID, key, err)
+				continue
+			}
+			for _, partial := range completed {
+				hit := pattern.hit(partial)
+				if pattern.State == ruleStateShadow {
+					shadowHits = append(shadowHits, hit)
+				} else {
+					hits = append(hits, hit)
+				}
+			}
+		}
+	}
+	return hits, shadowHits
+}
+
+// keys returns the distinct values the transaction has for the pattern's step
+// keys: the sequences it could start or advance
+func (p *cepPattern) keys(features map[string]interface{}) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, step := range p.Steps {
+		key, ok := features[step.Key].(string)
+		if !ok || key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// keyed reports whether the transaction's value for step's key is key
+func (p *cepPattern) keyed(step int, features map[string]interface{}, key string) bool {
+	value, ok := features[p.Steps[step].Key].(string)
+	return ok && value == key
+}
+
+// advance updates the pattern's partial matches for key and returns the ones
+// the transaction completed
+func (e *cepEngine) advance(ctx context.Context, pattern *cepPattern, key string, txnID string, features map[string]interface{}, at time.Time) ([]*cepPartial, error) {
+	redisKey := "cep:{" + pattern.ID + ":" + key + "}"
+	var completed []*cepPartial
+	var started, expired, dropped, stale int64
+
+	err := e.store.update(ctx, redisKey, pattern.within, func(partials []*cepPartial) ([]*cepPartial, bool) {
+		completed = nil
+		started, expired, dropped, stale = 0, 0, 0, 0
+		var kept []*cepPartial
+		changed := false
+		for _, partial := range partials {
+			// Matched against an older definition of the pattern (or corrupt):
+			// its steps no longer line up with the current ones
+			if !pattern.valid(partial) {
+				stale++
+				changed = true
+				continue
+			}
+			if partial.Captured == nil {
+				partial.Captured = make(map[string]interface{})
+			}
+			if at.Sub(time.UnixMilli(partial.StartedAt)) > pattern.within {
+				expired++
+				changed = true
+				continue
+			}
+			// A step can't come before the step it follows
+			if at.UnixMilli() >= partial.LastAt && pattern.keyed(partial.Next, features, key) &&
+				pattern.matches(partial.Next, features, partial.Captured) {
+				pattern.extend(partial, txnID, features, at)
+				changed = true
+				if partial.Next == len(pattern.Steps) {
+					completed = append(completed, partial)
+					continue
+				}
+			}
+			kept = append(kept, partial)
+		}
+		if pattern.keyed(0, features, key) && pattern.matches(0, features, nil) {
+			partial := &cepPartial{Version: pattern.version, StartedAt: at.UnixMilli(), Captured: make(map[string]interface{})}
+			pattern.extend(partial, txnID, features, at)
+			kept = append(kept, partial)
+			started++
+			changed = true
+		}
+		if len(kept) > cepMaxPartials {
+			dropped = int64(len(kept) - cepMaxPartials)
+			kept = kept[len(kept)-cepMaxPartials:]
+		}
+		return kept, changed
+	})
+	if err != nil {
+		return nil, err
+	}
+	cepMetrics.Add("started", started)
+	cepMetrics.Add("completed", int64(len(completed)))
+	cepMetrics.Add("expired", expired)
+	cepMetrics.Add("dropped", dropped)
+	cepMetrics.Add("stale", stale)
+	return completed, nil
+}
+
+// valid reports whether a stored partial match belongs to this definition of
+// the pattern and is still in progress
+func (p *cepPattern) valid(partial *cepPartial) bool {
+	return partial.Version == p.version &&
+		partial.Next > 0 && partial.Next < len(p.Steps) &&
+		len(partial.Txns) == partial.Next
+}
+
+func (p *cepPattern) matches(step int, features map[string]interface{}, captured map[string]interface{}) bool {
+	if len(captured) > 0 {
+		merged := make(map[string]interface{}, len(features)+len(captured))
+		for name, value := range features {
+			merged[name] = value
+		}
+		for name, value := range captured {
+			merged["captured."+name] = value
+		}
+		features = merged
+	}
+	result, err := p.Steps[step].compiled.eval(features)
+	if err != nil {
+		log.Printf("Pattern %s step %s evaluation failed: %v", p.ID, p.Steps[step].Name, err)
+		return false
+	}
+	matched, ok := result.(bool)
+	return ok && matched
+}
+
+// extend records the transaction as the partial's next step
+func (p *cepPattern) extend(partial *cepPartial, txnID string, features map[string]interface{}, at time.Time) {
+	for name, feature := range p.Steps[partial.Next].Capture {
+		partial.Captured[name] = features[feature]
+	}
+	partial.Txns = append(partial.Txns, txnID)
+	partial.LastAt = at.UnixMilli()
+	partial.Next++
+}
+
+// hit describes a completed sequence: the transaction behind every step and
+// how long the sequence took against the pattern's bound
+func (p *cepPattern) hit(partial *cepPartial) *pb.RuleHit {
+	hit := &pb.RuleHit{
+		RuleId:     p.ID,
+		Severity:   p.Severity,
+		ReasonCode: p.ReasonCode,
+		Action:     p.Action,
+		AlertType:  alertTypeFraud,
+	}
+	for i, step := range p.Steps {
+		hit.Conditions = append(hit.Conditions, &pb.ReasonCondition{
+			Feature:   "step." + step.Name + ".transaction_id",
+			Operator:  "==",
+			ValueText: partial.Txns[i],
+		})
+	}
+	elapsed := time.Duration(partial.LastAt-partial.StartedAt) * time.Millisecond
+	hit.Conditions = append(hit.Conditions, &pb.ReasonCondition{
+		Feature:   "sequence_seconds",
+		Operator:  "<=",
+		Value:     elapsed.Seconds(),
+		Threshold: p.within.Seconds(),
+	})
+	return hit
+}
+
+// reload re-reads the patterns file if it changed. Partial matches of a pattern whose keys, bound or
+// steps changed are discarded the next time its key is seen.
+func (e *cepEngine) reload() (bool, error) {
+	version, err := statVersion(e.path)
+	if err != nil {
+		return false, err
+	}
+	if e.current.Load() != nil && version == e.version {
+		return false, nil
+	}
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return false, err
+	}
+	set, err := parseCEPPatterns(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", e.path, err)
+	}
+	previous := e.current.Swap(set)
+	e.version = version
+	cepMetrics.Set("pattern_set_version", stringVar(set.Version))
+	if previous == nil {
+		log.Printf("Loaded pattern set %s (%d patterns) from %s", set.Version, len(set.Patterns), e.path)
+	} else {
+		log.Printf("Reloaded pattern set %s -> %s (%d patterns) from %s", previous.Version, set.Version, len(set.Patterns), e.path)
+	}
+	return true, nil
+}
+
+// watch polls the patterns file until ctx is cancelled
+func (e *cepEngine) watch(ctx context.Context) {
+	pollReload(ctx, "CEP_RELOAD_SECONDS", defaultCEPReload, func() {
+		if _, err := e.reload(); err != nil {
+			cepMetrics.Add("reload_errors", 1)
+			log.Printf("Pattern reload failed, keeping version %s: %v", e.Patterns().Version, err)
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// memoryCEPStore keeps partial matches in a map, serialized as in Redis so a
+// test can't share partials with the engine
+type memoryCEPStore map[string][]byte
+
+func (s memoryCEPStore) update(_ context.Context, key string, _ time.Duration, fn func([]*cepPartial) ([]*cepPartial, bool)) error {
+	var partials []*cepPartial
+	if data, ok := s[key]; ok {
+		if err := json.Unmarshal(data, &partials); err != nil {
+			return err
+		}
+	}
+	kept, changed := fn(partials)
+	if !changed {
+		return nil
+	}
+	if len(kept) == 0 {
+		delete(s, key)
+		return nil
+	}
+	data, err := json.Marshal(kept)
+	if err != nil {
+		return err
+	}
+	s[key] = data
+	return nil
+}
+
+func (s memoryCEPStore) partials(t *testing.T, key string) []*cepPartial {
+	t.Helper()
+	var partials []*cepPartial
+	if data, ok := s[key]; ok {
+		if err := json.Unmarshal(data, &partials); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return partials
+}
+
+// newTestCEPEngine serves the patterns in patterns.yaml from an in-memory store
+func newTestCEPEngine(t *testing.T) (*cepEngine, memoryCEPStore) {
+	t.Helper()
+	store := memoryCEPStore{}
+	e := &cepEngine{store: store}
+	e.current.Store(loadTestPatterns(t, nil))
+	return e, store
+}
+
+// loadTestPatterns parses patterns.yaml, letting edit change it first
+func loadTestPatterns(t *testing.T, edit func(*cepPatternSet)) *cepPatternSet {
+	t.Helper()
+	data, err := os.ReadFile("patterns.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := parseCEPPatterns(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edit != nil {
+		edit(set)
+		data, err := yaml.Marshal(set)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if set, err = parseCEPPatterns(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return set
+}
+
+func cepTransaction(txnType, user, dest string, amount float64, drained bool) map[string]interface{} {
+	return map[string]interface{}{
+		"transaction.type":         txnType,
+		"transaction.user_id":      user,
+		"transaction.dest_account": dest,
+		"transaction.amount":       amount,
+		"balance.orig_drained":     drained,
+	}
+}
+
+const drainKey = "cep:{DRAIN_THEN_CASH_OUT:C-mule}"
+
+// A drain into the mule account followed by the mule cashing out the amount
+func TestCEPDrainThenCashOut(t *testing.T) {
+	e, store := newTestCEPEngine(t)
+	ctx := context.Background()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	hits, _ := e.Process(ctx, "t1", cepTransaction("TRANSFER", "C-victim", "C-mule", 500, true), start)
+	if len(hits) != 0 {
+		t.Fatalf("the first step hit: %v", hits)
+	}
+	partials := store.partials(t, drainKey)
+	if len(partials) != 1 || partials[0].Next != 1 || partials[0].Captured["amount"] != 500.0 {
+		t.Fatalf("after the drain %s holds %+v, want one partial at step 1 that captured 500", drainKey, partials)
+	}
+
+	// The victim cashing out, and the mule cashing out another amount, don't
+	// advance it
+	for i, txn := range []map[string]interface{}{
+		cepTransaction("CASH_OUT", "C-victim", "C-other", 500, false),
+		cepTransaction("CASH_OUT", "C-mule", "C-other", 320, false),
+	} {
+		if hits, _ := e.Process(ctx, "unrelated", txn, start.Add(time.Duration(i+1)*time.Minute)); len(hits) != 0 {
+			t.Fatalf("unrelated cash out %d hit: %v", i, hits)
+		}
+	}
+	if partials := store.partials(t, drainKey); len(partials) != 1 || partials[0].Next != 1 {
+		t.Fatalf("unrelated cash outs changed the partial: %+v", partials)
+	}
+
+	hits, _ = e.Process(ctx, "t2", cepTransaction("CASH_OUT", "C-mule", "C-other", 500.004, false), start.Add(10*time.Minute))
+	if len(hits) != 1 {
+		t.Fatalf("got %d hits for the cash out, want 1", len(hits))
+	}
+	want := map[string]string{"step.drain.transaction_id": "t1", "step.cash_out.transaction_id": "t2"}
+	for _, condition := range hits[0].Conditions {
+		if txnID, ok := want[condition.Feature]; ok && condition.ValueText != txnID {
+			t.Errorf("%s = %s, want %s", condition.Feature, condition.ValueText, txnID)
+		}
+		if condition.Feature == "sequence_seconds" && condition.Value != 600 {
+			t.Errorf("sequence_seconds = %v, want 600", condition.Value)
+		}
+	}
+	if hits[0].RuleId != "DRAIN_THEN_CASH_OUT" || hits[0].Action != "review" {
+		t.Errorf("hit = %s/%s, want DRAIN_THEN_CASH_OUT/review", hits[0].RuleId, hits[0].Action)
+	}
+	// A completed sequence is done with
+	if _, ok := store[drainKey]; ok {
+		t.Fatalf("%s still holds %s after completing", drainKey, store[drainKey])
+	}
+}
+
+func TestCEPPartialMatchesLapse(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		at      time.Time // of the cash out
+		reload  func(*cepPatternSet)
+		wantHit bool
+	}{
+		{"within the bound", start.Add(30 * time.Minute), nil, true},
+		{"expired", start.Add(30*time.Minute + time.Millisecond), nil, false},
+		{"before the drain", start.Add(-time.Second), nil, false},
+		// The partial was matched against a definition that has since changed
+		{"stale version", start.Add(time.Minute), func(set *cepPatternSet) { set.Patterns[0].Within = "45m" }, false},
+		{"unchanged definition", start.Add(time.Minute), func(*cepPatternSet) {}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, store := newTestCEPEngine(t)
+			ctx := context.Background()
+			e.Process(ctx, "t1", cepTransaction("TRANSFER", "C-victim", "C-mule", 500, true), start)
+			if tt.reload != nil {
+				e.current.Store(loadTestPatterns(t, tt.reload))
+			}
+
+			hits, _ := e.Process(ctx, "t2", cepTransaction("CASH_OUT", "C-mule", "C-other", 500, false), tt.at)
+			if (len(hits) == 1) != tt.wantHit || len(hits) > 1 {
+				t.Fatalf("got %d hits, want hit %v", len(hits), tt.wantHit)
+			}
+			// Expired and stale partials are dropped rather than kept around
+			if partials := store.partials(t, drainKey); !tt.wantHit && tt.at.After(start) && len(partials) != 0 {
+				t.Fatalf("%s holds %+v, want it cleared", drainKey, partials)
+			}
+		})
+	}
+}
+
+// Shadow patterns complete into shadow hits only
+func TestCEPShadowPattern(t *testing.T) {
+	e, _ := newTestCEPEngine(t)
+	e.current.Store(loadTestPatterns(t, func(set *cepPatternSet) { set.Patterns[0].State = ruleStateShadow }))
+	ctx := context.Background()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	e.Process(ctx, "t1", cepTransaction("TRANSFER", "C-victim", "C-mule", 500, true), start)
+	hits, shadowHits := e.Process(ctx, "t2", cepTransaction("CASH_OUT", "C-mule", "C-other", 500, false), start.Add(time.Minute))
+	if len(hits) != 0 || len(shadowHits) != 1 {
+		t.Fatalf("got %d hits and %d shadow hits, want 0 and 1", len(hits), len(shadowHits))
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Time that affects what the enricher emits (cooldowns, created_at stamps, the event time of untimestamped transactions, the watermark cap) is
+// read from `clock` rather than time.Now(). Windowed features themselves run
+// on event time (event_time.go).
+//
+// CLOCK_MODE selects the clock:
+//
+//	wall       (default) the system clock
+//	simulated  time is the latest accepted partition watermark, so a
+//	           historical stream can be replayed as fast as Kafka delivers it
+//	           and produce the same output as it did live. Late events don't
+//	           move it, and like the watermark it advances at most
+//	           watermarkMaxFuture per event, so one transaction stamped far in
+//	           the future can't jump every cooldown and TTL ahead.
+//
+// Latency metrics, reload polling and the in-memory geo cache TTL always use
+// the system clock; they are about the process, not the stream. Redis TTLs only garbage collect keys;
+// anything whose expiry changes a result checks the clock itself. For
+// identical results a replay has to start from empty Redis state.
+type Clock interface {
+	Now() time.Time
+	// Observe reports the watermark after a transaction was accepted for
+	// processing
+	Observe(watermark time.Time)
+}
+
+var clock Clock = wallClock{}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time { return time.Now() }
+
+func (wallClock) Observe(time.Time) {}
+
+// simulatedClock only moves forward: an out of order event doesn't take time
+// back, so cooldowns and TTLs behave as they would have live
+type simulatedClock struct {
+	nanos atomic.Int64
+}
+
+func (c *simulatedClock) Now() time.Time {
+	return time.Unix(0, c.nanos.Load())
+}
+
+func (c *simulatedClock) Observe(watermark time.Time) {
+	next := watermark.UnixNano()
+	for {
+		current := c.nanos.Load()
+		if next <= current || c.nanos.CompareAndSwap(current, next) {
+			return
+		}
+	}
+}
+
+// setupClock installs the clock selected by CLOCK_MODE
+func setupClock() {
+	switch mode := os.Getenv("CLOCK_MODE"); mode {
+	case "", "wall":
+		clock = wallClock{}
+	case "simulated":
+		log.Printf("CLOCK_MODE=simulated: time follows transaction event time")
+		clock = &simulatedClock{}
+	default:
+		log.Fatalf("Unknown CLOCK_MODE %q (wall, simulated)", mode)
+	}
+}
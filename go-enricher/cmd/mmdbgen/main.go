@@ -0,0 +1,34 @@
+// mmdbgen builds small GeoLite2-compatible City and ASN .mmdb files from a
+// YAML fixture (see internal/mmdbfixture), so geo enrichment can be exercised
+// against known IPs without the real (licensed) databases:
+//
+//	go run ./cmd/mmdbgen -fixture cmd/mmdbgen/fixture.yaml -out /tmp/geoip
+//
+// writes /tmp/geoip/GeoLite2-City.mmdb and /tmp/geoip/GeoLite2-ASN.mmdb. The
+// build epoch comes from the fixture, so the same fixture always produces the
+// same bytes. Every fixture network is looked up again in the written files
+// and checked against the fixture before the command succeeds.
+package main
+
+import (
+	"flag"
+	"log"
+
+	"fraud-enricher/internal/mmdbfixture"
+)
+
+func main() {
+	fixturePath := flag.String("fixture", "cmd/mmdbgen/fixture.yaml", "YAML fixture of networks")
+	outDir := flag.String("out", ".", "directory for GeoLite2-City.mmdb and GeoLite2-ASN.mmdb")
+	flag.Parse()
+
+	fx, err := mmdbfixture.Load(*fixturePath)
+	if err != nil {
+		log.Fatalf("Failed to load fixture: %v", err)
+	}
+	cityPath, asnPath, err := mmdbfixture.Write(*outDir, fx)
+	if err != nil {
+		log.Fatalf("Failed to build databases: %v", err)
+	}
+	log.Printf("Wrote %s and %s (%d networks)", cityPath, asnPath, len(fx.Networks))
+}
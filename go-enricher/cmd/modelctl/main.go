@@ -0,0 +1,223 @@
+// modelctl manages the enricher's filesystem model registry:
+//
+//	go run ./cmd/modelctl -registry models list
+//	go run ./cmd/modelctl -registry models promote xgb-2024-07-01
+//	go run ./cmd/modelctl -registry models challengers lgb-2024-07-01 xgb-2024-07-02
+//	go run ./cmd/modelctl -registry models challengers          # clear
+//
+// promote and challengers rewrite promoted.yaml atomically (write + rename), so
+// the enricher never reads a half written pointer. Versions must exist under
+// versions/<version>/manifest.yaml; the enricher still runs each model's
+// reference check before putting it in service.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type modelPointer struct {
+	Champion    string   `yaml:"champion"`
+	Challengers []string `yaml:"challengers"`
+}
+
+type manifest struct {
+	Version  string            `yaml:"version"`
+	Format   string            `yaml:"format"`
+	Metadata map[string]string `yaml:"metadata"`
+}
+
+const pointerHeader = `# Models in service. champion is scored and acted on; challengers are scored
+# in shadow and recorded on the event (challenger_scores) only.
+# Edit with ` + "`go run ./cmd/modelctl`" + ` or by hand; re-read every MODEL_RELOAD_SECONDS.
+
+`
+
+func main() {
+	registry := flag.String("registry", "models", "model registry directory")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: modelctl [-registry DIR] list | promote VERSION | challengers [VERSION...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "list":
+		err = list(*registry)
+	case "promote":
+		if len(args) != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = update(*registry, func(p *modelPointer) error {
+			if err := checkVersion(*registry, args[0]); err != nil {
+				return err
+			}
+			previous := p.Champion
+			p.Champion = args[0]
+			p.Challengers = without(p.Challengers, args[0])
+			log.Printf("Promoted %s (was %s)", args[0], previous)
+			return nil
+		})
+	case "challengers":
+		err = update(*registry, func(p *modelPointer) error {
+			p.Challengers = nil
+			for _, version := range args {
+				if err := checkVersion(*registry, version); err != nil {
+					return err
+				}
+				if version != p.Champion {
+					p.Challengers = append(p.Challengers, version)
+				}
+			}
+			log.Printf("Challengers: %v", p.Challengers)
+			return nil
+		})
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readManifest(registry, version string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(registry, "versions", version, "manifest.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("version %s: %w", version, err)
+	}
+	return &m, nil
+}
+
+func checkVersion(registry, version string) error {
+	m, err := readManifest(registry, version)
+	if err != nil {
+		return err
+	}
+	if m.Version != version {
+		return fmt.Errorf("version %s: manifest says %q", version, m.Version)
+	}
+	return nil
+}
+
+func readPointer(registry string) (*modelPointer, error) {
+	var p modelPointer
+	data, err := os.ReadFile(filepath.Join(registry, "promoted.yaml"))
+	if os.IsNotExist(err) {
+		return &p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func update(registry string, change func(*modelPointer) error) error {
+	p, err := readPointer(registry)
+	if err != nil {
+		return err
+	}
+	if err := change(p); err != nil {
+		return err
+	}
+	if p.Champion == "" {
+		return fmt.Errorf("no champion promoted yet, run promote first")
+	}
+	if p.Challengers == nil {
+		p.Challengers = []string{}
+	}
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(registry, "promoted.yaml")
+	tmp, err := os.CreateTemp(registry, ".promoted-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(pointerHeader + string(data)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func list(registry string) error {
+	p, err := readPointer(registry)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(filepath.Join(registry, "versions"))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		version := entry.Name()
+		role := ""
+		switch {
+		case version == p.Champion:
+			role = "champion"
+		case contains(p.Challengers, version):
+			role = "challenger"
+		}
+		m, err := readManifest(registry, version)
+		if err != nil {
+			fmt.Printf("%-28s %-10s (unreadable: %v)\n", version, role, err)
+			continue
+		}
+		var meta []string
+		for k, v := range m.Metadata {
+			meta = append(meta, k+"="+v)
+		}
+		sort.Strings(meta)
+		fmt.Printf("%-28s %-10s %-9s %s\n", version, role, m.Format, strings.Join(meta, " "))
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func without(list []string, s string) []string {
+	var out []string
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// csvGeoProvider serves geo data from two local CSV files, for CI and sites
+// without a MaxMind licence (GEO_PROVIDER=csv):
+//
+//	GEO_CSV_LOCATIONS  network,city,country,country_code,latitude,longitude
+//	GEO_CSV_ASN        network,asn,organization
+//
+// network is a CIDR (IPv4 or IPv6). Both files take an optional header row and
+// # comment lines. Overlapping networks are allowed; the longest prefix wins.
+const (
+	defaultGeoCSVLocations = "/data/geo-csv/locations.csv"
+	defaultGeoCSVASN       = "/data/geo-csv/asn.csv"
+)
+
+type csvLocation struct {
+	city, country, countryCode string
+	latitude, longitude        float64
+}
+
+type csvASN struct {
+	number       uint
+	organization string
+}
+
+type csvGeoProvider struct {
+	locations *prefixTrie[csvLocation]
+	asns      *prefixTrie[csvASN]
+	version   string // hash of both files
+}
+
+func newCSVGeoProvider() (*csvGeoProvider, error) {
+	locationsPath := os.Getenv("GEO_CSV_LOCATIONS")
+	if locationsPath == "" {
+		locationsPath = defaultGeoCSVLocations
+	}
+	asnPath := os.Getenv("GEO_CSV_ASN")
+	if asnPath == "" {
+		asnPath = defaultGeoCSVASN
+	}
+
+	p := &csvGeoProvider{locations: newPrefixTrie[csvLocation](), asns: newPrefixTrie[csvASN]()}
+	sum := sha256.New()
+	err := readGeoCSV(locationsPath, 6, sum, func(network netip.Prefix, row []string) error {
+		lat, err := strconv.ParseFloat(row[4], 64)
+		if err != nil {
+			return fmt.Errorf("latitude: %w", err)
+		}
+		lon, err := strconv.ParseFloat(row[5], 64)
+		if err != nil {
+			return fmt.Errorf("longitude: %w", err)
+		}
+		return p.locations.Insert(network, csvLocation{city: row[1], country: row[2], countryCode: row[3], latitude: lat, longitude: lon})
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = readGeoCSV(asnPath, 3, sum, func(network netip.Prefix, row []string) error {
+		number, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(row[1]), "AS"), 10, 32)
+		if err != nil {
+			return fmt.Errorf("asn: %w", err)
+		}
+		return p.asns.Insert(network, csvASN{number: uint(number), organization: row[2]})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.version = hex.EncodeToString(sum.Sum(nil))[:12]
+	log.Printf("Loaded CSV geo data (version %s): %d location networks from %s, %d ASN networks from %s",
+		p.version, p.locations.Len(), locationsPath, p.asns.Len(), asnPath)
+	return p, nil
+}
+
+// readGeoCSV calls add for every data row of path and writes the file's bytes
+// to sum. A first row whose network column doesn't parse is taken as the
+// header.
+func readGeoCSV(path string, columns int, sum hash.Hash, add func(netip.Prefix, []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(io.TeeReader(f, sum))
+	r.Comment = '#'
+	r.FieldsPerRecord = columns
+	r.TrimLeadingSpace = true
+	for first := true; ; first = false {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		network, err := netip.ParsePrefix(row[0])
+		if err != nil {
+			if first {
+				continue
+			}
+			line, _ := r.FieldPos(0)
+			return fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		if err := add(network.Masked(), row); err != nil {
+			line, _ := r.FieldPos(0)
+			return fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+	}
+}
+
+func (p *csvGeoProvider) Lookup(ip string) (*GeoData, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP %q", ip)
+	}
+	addr = addr.Unmap()
+
+	geo := &GeoData{}
+	loc, locNet, ok := p.locations.Lookup(addr)
+	if ok {
+		geo.City = loc.city
+		geo.Country = loc.country
+		geo.CountryCode = loc.countryCode
+		geo.Latitude = loc.latitude
+		geo.Longitude = loc.longitude
+	}
+	asn, asnNet, ok := p.asns.Lookup(addr)
+	if ok {
+		geo.ASN = fmt.Sprintf("AS%d", asn.number)
+		geo.ISP = asn.organization
+	}
+	// Both answers hold across the narrower of the two networks
+	network := locNet
+	if asnNet.Bits() > network.Bits() {
+		network = asnNet
+	}
+	geo.Network = network.String()
+	return geo, nil
+}
+
+func (p *csvGeoProvider) Name() string {
+	return "csv"
+}
+
+func (p *csvGeoProvider) Version() string {
+	return p.version
+}
+
+func (p *csvGeoProvider) Status() string {
+	return geoProviderOK
+}
+
+// prefixTrie is a binary trie over address bits for longest-prefix match.
+// IPv4 and IPv6 networks are kept in separate roots. It is built once and
+// only read afterwards, so lookups need no locking.
+type prefixTrie[V any] struct {
+	v4, v6 *trieNode[V]
+	size   int
+}
+
+type trieNode[V any] struct {
+	children [2]*trieNode[V]
+	value    V
+	set      bool
+}
+
+func newPrefixTrie[V any]() *prefixTrie[V] {
+	return &prefixTrie[V]{v4: &trieNode[V]{}, v6: &trieNode[V]{}}
+}
+
+func (t *prefixTrie[V]) root(addr netip.Addr) *trieNode[V] {
+	if addr.Is4() {
+		return t.v4
+	}
+	return t.v6
+}
+
+// ipv4MappedRange holds the IPv4-mapped IPv6 addresses (::ffff:0:0/96)
+var ipv4MappedRange = netip.MustParsePrefix("::ffff:0:0/96")
+
+// Insert adds network (which must be masked); a duplicate network replaces
+// the earlier value. An IPv4-mapped IPv6 network (::ffff:1.2.3.0/120) is
+// stored as the IPv4 network it maps (1.2.3.0/24); one wider than the mapped
+// range (shorter than /96) is rejected.
+func (t *prefixTrie[V]) Insert(network netip.Prefix, value V) error {
+	addr, bits := network.Addr(), network.Bits()
+	if network.Overlaps(ipv4MappedRange) {
+		if bits < 96 {
+			return fmt.Errorf("network %s is wider than the IPv4-mapped range", network)
+		}
+		addr, bits = addr.Unmap(), bits-96
+	}
+	node := t.root(addr)
+	bytes := addr.AsSlice()
+	for i := 0; i < bits; i++ {
+		bit := bytes[i/8] >> (7 - i%8) & 1
+		if node.children[bit] == nil {
+			node.children[bit] = &trieNode[V]{}
+		}
+		node = node.children[bit]
+	}
+	if !node.set {
+		t.size++
+	}
+	node.value, node.set = value, true
+	return nil
+}
+
+// Lookup returns the value of the longest prefix containing addr, and the
+// largest network around addr that gets this same answer: the path stops
+// where the trie has no deeper entries, so that network holds no
+// more specific prefix. Like a MaxMind lookup, it is safe to cache the
+// result for that whole network, including when nothing matched.
+func (t *prefixTrie[V]) Lookup(addr netip.Addr) (V, netip.Prefix, bool) {
+	node := t.root(addr)
+	bytes := addr.AsSlice()
+	var best *trieNode[V]
+	depth := 0
+	for {
+		if node.set {
+			best = node
+		}
+		if depth == len(bytes)*8 {
+			break
+		}
+		next := node.children[bytes[depth/8]>>(7-depth%8)&1]
+		if next == nil {
+			// Nothing below on addr's side of this bit; a leaf is uniform as a whole
+			if node.children[0] != nil || node.children[1] != nil {
+				depth++
+			}
+			break
+		}
+		node = next
+		depth++
+	}
+	network, _ := addr.Prefix(depth)
+	if best == nil {
+		var zero V
+		return zero, network, false
+	}
+	return best.value, network, true
+}
+
+func (t *prefixTrie[V]) Len() int {
+	return t.size
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestPrefixTrie(t *testing.T) {
+	trie := newPrefixTrie[string]()
+	for _, entry := range []struct{ network, value string }{
+		{"10.0.0.0/8", "ten"},
+		{"10.1.0.0/16", "ten-one"},
+		{"::ffff:1.2.3.0/120", "mapped"},
+		{"2001:db8::/32", "doc6"},
+		{"198.51.100.7/32", "host"},
+		{"2001:db8:1::/48", "doc6-1"},
+		{"::ffff:10.1.0.0/112", "ten-one-again"},
+	} {
+		if err := trie.Insert(netip.MustParsePrefix(entry.network).Masked(), entry.value); err != nil {
+			t.Fatalf("Insert(%s): %v", entry.network, err)
+		}
+	}
+	// ::ffff:10.1.0.0/112 is 10.1.0.0/16 again, so it replaced that entry
+	if trie.Len() != 6 {
+		t.Fatalf("Len() = %d, want 6", trie.Len())
+	}
+
+	tests := []struct {
+		addr    string
+		value   string // "" for no match
+		network string // the uniform network around addr
+	}{
+		{"10.1.2.3", "ten-one-again", "10.1.0.0/16"},
+		// Under 10/8 but beside 10.1/16: the answer holds for 10.2.0.0/15
+		{"10.2.3.4", "ten", "10.2.0.0/15"},
+		{"10.200.0.1", "ten", "10.128.0.0/9"},
+		{"1.2.3.4", "mapped", "1.2.3.0/24"},
+		{"198.51.100.7", "host", "198.51.100.7/32"},
+		{"2001:db8:2::1", "doc6", "2001:db8:2::/47"},
+		{"2001:db8:1::1", "doc6-1", "2001:db8:1::/48"},
+		// No match: the miss is still uniform over a network
+		{"192.168.1.1", "", "192.0.0.0/6"},
+		{"2001:db9::1", "", "2001:db9::/32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			value, network, ok := trie.Lookup(netip.MustParseAddr(tt.addr))
+			if ok != (tt.value != "") || value != tt.value || network.String() != tt.network {
+				t.Errorf("Lookup(%s) = %q, %s, %v; want %q, %s", tt.addr, value, network, ok, tt.value, tt.network)
+			}
+		})
+	}
+}
+
+func TestPrefixTrieRejectsWideMappedNetwork(t *testing.T) {
+	trie := newPrefixTrie[string]()
+	for _, network := range []string{"::ffff:0.0.0.0/95", "::/80"} {
+		if err := trie.Insert(netip.MustParsePrefix(network).Masked(), "x"); err == nil {
+			t.Errorf("Insert(%s) covering the IPv4-mapped range succeeded", network)
+		}
+	}
+	if trie.Len() != 0 {
+		t.Fatalf("Len() = %d after a rejected insert", trie.Len())
+	}
+}
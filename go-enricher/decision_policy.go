@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	pb "fraud-enricher/pb"
+)
+
+// The decision stage turns everything known about a transaction into one
+// verdict. Rule hits, the champion model's score and reputation signals are
+// combined on the log-odds scale into a calibrated risk score:
+//
+//	logit(risk) = bias + model_weight * logit(fraud_score)   (logit(prior) if unscored)
+//	            + sum of matched rule weights
+//	            + sum of matched signal weights
+//
+// and thresholds map the risk to ALLOW / REVIEW / BLOCK. AML rule hits are
+// left to the AML alerts and don't count. Every positive
+// contribution becomes a Reason (largest first) carrying the conditions that
+// triggered it, each with the feature value and threshold. The policy lives in
+// DECISION_POLICY_FILE and is re-read every DECISION_POLICY_RELOAD_SECONDS.
+const (
+	defaultDecisionPolicyFile   = "decision_policy.yaml"
+	defaultDecisionPolicyReload = 30 * time.Second
+
+	outcomeAllow  = "ALLOW"
+	outcomeReview = "REVIEW"
+	outcomeBlock  = "BLOCK"
+
+	// Keeps logit finite for model scores of exactly 0 or 1
+	decisionScoreClamp = 1e-6
+)
+
+var outcomeRank = map[string]int{outcomeAllow: 0, outcomeReview: 1, outcomeBlock: 2}
+
+type decisionSignal struct {
+	ID         string  `yaml:"id"`
+	Expression string  `yaml:"expression"`
+	Weight     float64 `yaml:"weight"`
+	ReasonCode string  `yaml:"reason_code"`
+
+	compiled exprNode
+}
+
+type DecisionPolicy struct {
+	Version string `yaml:"version"`
+
+	Bias            float64 `yaml:"bias"`
+	Prior           float64 `yaml:"prior"`
+	ModelWeight     float64 `yaml:"model_weight"`
+	ModelReasonCode string  `yaml:"model_reason_code"`
+
+	SeverityWeights map[string]float64 `yaml:"severity_weights"`
+	RuleWeights     map[string]float64 `yaml:"rule_weights"` // per rule id, overrides severity
+	Signals         []*decisionSignal  `yaml:"signals"`
+
+	Thresholds struct {
+		Review float64 `yaml:"review"`
+		Block  float64 `yaml:"block"`
+	} `yaml:"thresholds"`
+
+	// Rule actions review / block act as a floor on the outcome
+	HonorRuleActions bool `yaml:"honor_rule_actions"`
+}
+
+// decisionResult is the verdict for one transaction
+type decisionResult struct {
+	Outcome     string
+	RiskScore   float64
+	Reasons     []*pb.Reason
+	ReasonCodes []string // distinct codes of Reasons, in order
+}
+
+func parseDecisionPolicy(data []byte) (*DecisionPolicy, error) {
+	policy := &DecisionPolicy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("parse decision policy: %w", err)
+	}
+	if policy.Version == "" {
+		policy.Version = "sha256:" + contentHash(data)[:12]
+	}
+	if policy.Prior <= 0 || policy.Prior >= 1 {
+		return nil, fmt.Errorf("prior must be in (0, 1), got %v", policy.Prior)
+	}
+	if policy.ModelReasonCode == "" {
+		policy.ModelReasonCode = "MODEL_HIGH_RISK"
+	}
+	review, block := policy.Thresholds.Review, policy.Thresholds.Block
+	if review <= 0 || review > block || block > 1 {
+		return nil, fmt.Errorf("thresholds must satisfy 0 < review <= block <= 1, got review=%v block=%v", review, block)
+	}
+	for severity := range policy.SeverityWeights {
+		if !validSeverities[severity] {
+			return nil, fmt.Errorf("unknown severity %q in severity_weights", severity)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, signal := range policy.Signals {
+		if signal.ID == "" {
+			return nil, fmt.Errorf("signal #%d has no id", i)
+		}
+		if seen[signal.ID] {
+			return nil, fmt.Errorf("duplicate signal id %q", signal.ID)
+		}
+		seen[signal.ID] = true
+		if signal.ReasonCode == "" {
+			signal.ReasonCode = signal.ID
+		}
+		compiled, err := compileExpr(signal.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("signal %s: %w", signal.ID, err)
+		}
+		signal.compiled = compiled
+	}
+	return policy, nil
+}
+
+func logit(p float64) float64 {
+	p = math.Min(math.Max(p, decisionScoreClamp), 1-decisionScoreClamp)
+	return math.Log(p / (1 - p))
+}
+
+func (p *DecisionPolicy) ruleWeight(hit *pb.RuleHit) float64 {
+	if weight, ok := p.RuleWeights[hit.RuleId]; ok {
+		return weight
+	}
+	return p.SeverityWeights[hit.Severity]
+}
+
+// Decide scores the enriched transaction. Shadow rule hits never count.
+func (p *DecisionPolicy) Decide(enriched *pb.EnrichedTransaction, features map[string]interface{}) decisionResult {
+	var reasons []*pb.Reason
+	base := logit(p.Prior)
+	total := p.Bias + base
+	if enriched.ModelVersion != "" {
+		// Relative to the prior, so an unremarkable score adds nothing
+		model := p.ModelWeight*logit(enriched.FraudScore) - base
+		total += model
+		reasons = append(reasons, &pb.Reason{
+			Code:         p.ModelReasonCode,
+			Source:       "model",
+			SourceId:     enriched.ModelVersion,
+			Contribution: model,
+			Conditions: []*pb.ReasonCondition{{
+				Feature:   "fraud_score",
+				Operator:  ">",
+				Value:     enriched.FraudScore,
+				Threshold: p.Prior,
+			}},
+		})
+	}
+
+	floor := outcomeAllow
+	for _, hit := range enriched.RuleHits {
+		if hit.AlertType == alertTypeAML {
+			continue
+		}
+		weight := p.ruleWeight(hit)
+		total += weight
+		reasons = append(reasons, &pb.Reason{
+			Code:         hit.ReasonCode,
+			Source:       "rule",
+			SourceId:     hit.RuleId,
+			Contribution: weight,
+			Conditions:   hit.Conditions,
+		})
+		if !p.HonorRuleActions {
+			continue
+		}
+		switch hit.Action {
+		case "block":
+			floor = outcomeBlock
+		case "review":
+			if outcomeRank[floor] < outcomeRank[outcomeReview] {
+				floor = outcomeReview
+			}
+		}
+	}
+
+	for _, signal := range p.Signals {
+		result, err := signal.compiled.eval(features)
+		if err != nil {
+			log.Printf("Decision signal %s evaluation failed: %v", signal.ID, err)
+			continue
+		}
+		if matched, ok := result.(bool); ok && matched {
+			total += signal.Weight
+			reasons = append(reasons, &pb.Reason{
+				Code:         signal.ReasonCode,
+				Source:       "signal",
+				SourceId:     signal.ID,
+				Contribution: signal.Weight,
+				Conditions:   explainExpr(signal.compiled, features),
+			})
+		}
+	}
+
+	risk := sigmoid(total)
+	outcome := outcomeAllow
+	switch {
+	case risk >= p.Thresholds.Block:
+		outcome = outcomeBlock
+	case risk >= p.Thresholds.Review:
+		outcome = outcomeReview
+	}
+	if outcomeRank[floor] > outcomeRank[outcome] {
+		outcome = floor
+	}
+
+	result := decisionResult{Outcome: outcome, RiskScore: risk, Reasons: orderedReasons(reasons)}
+	seen := make(map[string]bool)
+	for _, reason := range result.Reasons {
+		if !seen[reason.Code] {
+			seen[reason.Code] = true
+			result.ReasonCodes = append(result.ReasonCodes, reason.Code)
+		}
+	}
+	return result
+}
+
+// orderedReasons keeps the reasons that raised the risk, largest
+// contribution first (ties by code, so the order is stable)
+func orderedReasons(reasons []*pb.Reason) []*pb.Reason {
+	var positive []*pb.Reason
+	for _, reason := range reasons {
+		if reason.Contribution > 0 {
+			positive = append(positive, reason)
+		}
+	}
+	sort.SliceStable(positive, func(i, j int) bool {
+		if positive[i].Contribution != positive[j].Contribution {
+			return positive[i].Contribution > positive[j].Contribution
+		}
+		return positive[i].Code < positive[j].Code
+	})
+	return positive
+}
+
+// decisionEngine holds the live policy and swaps it when the file changes
+type decisionEngine struct {
+	current atomic.Pointer[DecisionPolicy]
+	path    string
+	version fileVersion
+}
+
+func newDecisionEngine() (*decisionEngine, error) {
+	e := &decisionEngine{path: os.Getenv("DECISION_POLICY_FILE")}
+	if e.path == "" {
+		e.path = defaultDecisionPolicyFile
+	}
+	if _, err := e.reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *decisionEngine) Policy() *DecisionPolicy {
+	return e.current.Load()
+}
+
+// reload re-reads the policy file if it changed
+func (e *decisionEngine) reload() (bool, error) {
+	version, err := statVersion(e.path)
+	if err != nil {
+		return false, err
+	}
+	if e.current.Load() != nil && version == e.version {
+		return false, nil
+	}
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return false, err
+	}
+	policy, err := parseDecisionPolicy(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", e.path, err)
+	}
+	previous := e.current.Swap(policy)
+	e.version = version
+	decisionMetrics.Set("policy_version", stringVar(policy.Version))
+	if previous == nil {
+		log.Printf("Loaded decision policy %s from %s (review >= %.2f, block >= %.2f)", policy.Version, e.path, policy.Thresholds.Review, policy.Thresholds.Block)
+	} else {
+		log.Printf("Reloaded decision policy %s -> %s from %s (review >= %.2f, block >= %.2f)", previous.Version, policy.Version, e.path, policy.Thresholds.Review, policy.Thresholds.Block)
+	}
+	return true, nil
+}
+
+// watch polls the policy file until ctx is cancelled
+func (e *decisionEngine) watch(ctx context.Context) {
+	pollReload(ctx, "DECISION_POLICY_RELOAD_SECONDS", defaultDecisionPolicyReload, func() {
+		if _, err := e.reload(); err != nil {
+			decisionMetrics.Add("policy_reload_errors", 1)
+			log.Printf("Decision policy reload failed, keeping version %s: %v", e.Policy().Version, err)
+		}
+	})
+}
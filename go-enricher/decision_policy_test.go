@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	pb "fraud-enricher/pb"
+)
+
+// testDecisionPolicy has round weights so risks can be worked out by hand
+const testDecisionPolicy = `
+version: test
+bias: 0
+prior: 0.01
+model_weight: 1.0
+severity_weights:
+  low: 0.5
+  high: 3.0
+  critical: 6.0
+rule_weights:
+  HEAVY_RULE: 5.0
+signals:
+  - id: VPN
+    expression: anonymity.is_vpn
+    weight: 1.0
+    reason_code: VPN_IP
+thresholds:
+  review: 0.5
+  block: 0.9
+honor_rule_actions: %v
+`
+
+func TestDecide(t *testing.T) {
+	hit := func(id, severity, action, alertType string) *pb.RuleHit {
+		return &pb.RuleHit{RuleId: id, ReasonCode: id, Severity: severity, Action: action, AlertType: alertType}
+	}
+	prior := logit(0.01)
+
+	tests := []struct {
+		name     string
+		honor    bool
+		score    float64 // with model "m1"; ignored when unscored
+		unscored bool
+		hits     []*pb.RuleHit
+		shadow   []*pb.RuleHit
+		vpn      bool
+		outcome  string
+		risk     float64
+		codes    []string
+	}{
+		{name: "unscored starts from the prior", unscored: true, outcome: outcomeAllow, risk: 0.01},
+		{name: "scored takes the model's probability", score: 0.3, outcome: outcomeAllow, risk: 0.3, codes: []string{"MODEL_HIGH_RISK"}},
+		// Below the prior the model lowers the risk and is no reason
+		{name: "low score", score: 0.005, outcome: outcomeAllow, risk: 0.005},
+		{name: "review threshold", score: 0.6, outcome: outcomeReview, risk: 0.6, codes: []string{"MODEL_HIGH_RISK"}},
+		{name: "block threshold", score: 0.95, outcome: outcomeBlock, risk: 0.95, codes: []string{"MODEL_HIGH_RISK"}},
+		{name: "rule and signal", unscored: true, hits: []*pb.RuleHit{hit("IMPOSSIBLE_TRAVEL", "high", "alert", alertTypeFraud)}, vpn: true,
+			outcome: outcomeAllow, risk: sigmoid(prior + 3 + 1), codes: []string{"IMPOSSIBLE_TRAVEL", "VPN_IP"}},
+		{name: "rule weight overrides severity", unscored: true, hits: []*pb.RuleHit{hit("HEAVY_RULE", "low", "alert", alertTypeFraud)},
+			outcome: outcomeReview, risk: sigmoid(prior + 5), codes: []string{"HEAVY_RULE"}},
+		{name: "shadow hit doesn't count", unscored: true, shadow: []*pb.RuleHit{hit("NEW_RULE", "critical", "block", alertTypeFraud)},
+			outcome: outcomeAllow, risk: 0.01},
+		{name: "aml hit doesn't count", unscored: true, hits: []*pb.RuleHit{hit("STRUCTURING", "critical", "block", alertTypeAML)},
+			outcome: outcomeAllow, risk: 0.01},
+		{name: "rule action floors the outcome", honor: true, unscored: true, hits: []*pb.RuleHit{hit("BLOCKLIST", "low", "block", alertTypeFraud)},
+			outcome: outcomeBlock, risk: sigmoid(prior + 0.5), codes: []string{"BLOCKLIST"}},
+		{name: "review floor", honor: true, score: 0.2, hits: []*pb.RuleHit{hit("WATCHLIST", "low", "review", alertTypeFraud)},
+			outcome: outcomeReview, risk: sigmoid(logit(0.2) + 0.5), codes: []string{"MODEL_HIGH_RISK", "WATCHLIST"}},
+		// A floor never lowers the outcome
+		{name: "floor below the score", honor: true, score: 0.95, hits: []*pb.RuleHit{hit("WATCHLIST", "low", "review", alertTypeFraud)},
+			outcome: outcomeBlock, risk: sigmoid(logit(0.95) + 0.5), codes: []string{"MODEL_HIGH_RISK", "WATCHLIST"}},
+		{name: "rule action ignored", unscored: true, hits: []*pb.RuleHit{hit("BLOCKLIST", "low", "block", alertTypeFraud)},
+			outcome: outcomeAllow, risk: sigmoid(prior + 0.5), codes: []string{"BLOCKLIST"}},
+		// Equal contributions are ordered by code, and a code is listed once
+		{name: "ties", unscored: true, hits: []*pb.RuleHit{
+			hit("B_RULE", "high", "alert", alertTypeFraud),
+			hit("A_RULE", "high", "alert", alertTypeFraud),
+			hit("B_RULE", "high", "alert", alertTypeFraud),
+		}, outcome: outcomeBlock, risk: sigmoid(prior + 9), codes: []string{"A_RULE", "B_RULE"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := parseDecisionPolicy([]byte(fmt.Sprintf(testDecisionPolicy, tt.honor)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			enriched := &pb.EnrichedTransaction{
+				RuleHits:       tt.hits,
+				ShadowRuleHits: tt.shadow,
+				Anonymity:      &pb.AnonymitySignals{IsVpn: tt.vpn},
+			}
+			if !tt.unscored {
+				enriched.FraudScore, enriched.ModelVersion = tt.score, "m1"
+			}
+
+			result := policy.Decide(enriched, enrichedFeatures(enriched))
+			if result.Outcome != tt.outcome || math.Abs(result.RiskScore-tt.risk) > 1e-9 {
+				t.Errorf("Decide = %s at %.6f, want %s at %.6f", result.Outcome, result.RiskScore, tt.outcome, tt.risk)
+			}
+			if fmt.Sprint(result.ReasonCodes) != fmt.Sprint(tt.codes) {
+				t.Errorf("reason codes = %v, want %v", result.ReasonCodes, tt.codes)
+			}
+			for i := 1; i < len(result.Reasons); i++ {
+				if result.Reasons[i].Contribution > result.Reasons[i-1].Contribution {
+					t.Errorf("reason %s (%v) ranks below %s (%v)", result.Reasons[i].Code, result.Reasons[i].Contribution,
+						result.Reasons[i-1].Code, result.Reasons[i-1].Contribution)
+				}
+			}
+		})
+	}
+}
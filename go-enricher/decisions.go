@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"google.golang.org/protobuf/proto"
+
+	pb "fraud-enricher/pb"
+)
+
+const decisionsKafkaTopic = "fraud_decisions"
+
+// decisionPublisher sends one Decision per enriched transaction to the
+// decisions topic, keyed by user so a user's decisions stay in order.
+type decisionPublisher struct {
+	producer *kafka.Producer
+	topic    string
+}
+
+func newDecisionPublisher(producer *kafka.Producer) *decisionPublisher {
+	topic := os.Getenv("KAFKA_DECISIONS_TOPIC")
+	if topic == "" {
+		topic = decisionsKafkaTopic
+	}
+	return &decisionPublisher{producer: producer, topic: topic}
+}
+
+func (d *decisionPublisher) publish(enriched *pb.EnrichedTransaction, policy *DecisionPolicy, result decisionResult) (*pb.Decision, error) {
+	txn := enriched.Transaction
+	decision := &pb.Decision{
+		DecisionId:     contentHash([]byte(txn.TransactionId + "|" + policy.Version))[:32],
+		TransactionId:  txn.TransactionId,
+		UserId:         txn.UserId,
+		IpAddress:      txn.IpAddress,
+		Outcome:        result.Outcome,
+		RiskScore:      result.RiskScore,
+		ReasonCodes:    result.ReasonCodes,
+		Reasons:        result.Reasons,
+		PolicyVersion:  policy.Version,
+		RuleSetVersion: enriched.RuleSetVersion,
+		ModelVersion:   enriched.ModelVersion,
+		CreatedAt:      clock.Now().UnixMilli(),
+	}
+
+	value, err := proto.Marshal(decision)
+	if err != nil {
+		return decision, err
+	}
+	err = d.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{
+			Topic:     &d.topic,
+			Partition: kafka.PartitionAny,
+		},
+		Key:   []byte(txn.UserId),
+		Value: value,
+	}, nil)
+	if err != nil {
+		decisionMetrics.Add("publish_errors", 1)
+		return decision, err
+	}
+	decisionMetrics.Add(result.Outcome, 1)
+	return decision, nil
+}
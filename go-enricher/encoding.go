@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Output encodings for the enriched_transactions topic. The content type is sent
+// in the "content-type" header and the proto message name in the "schema" header
+// so consumers can decode without out-of-band configuration.
+const (
+	encodingProtobuf = "protobuf"
+	encodingJSON     = "json"
+	encodingAvro     = "avro"
+
+	defaultEnrichedEncoding = encodingJSON
+)
+
+type eventEncoder interface {
+	Encode(msg proto.Message) ([]byte, error)
+	Headers() []kafka.Header
+}
+
+type protobufEncoder struct {
+	headers []kafka.Header
+}
+
+func (e *protobufEncoder) Encode(msg proto.Message) ([]byte, error) {
+	return proto.Marshal(msg)
+}
+
+func (e *protobufEncoder) Headers() []kafka.Header {
+	return e.headers
+}
+
+// jsonEncoder uses protojson with the proto field names, so the JSON matches
+// the snake_case names in fraud.proto
+type jsonEncoder struct {
+	options protojson.MarshalOptions
+	headers []kafka.Header
+}
+
+func (e *jsonEncoder) Encode(msg proto.Message) ([]byte, error) {
+	return e.options.Marshal(msg)
+}
+
+func (e *jsonEncoder) Headers() []kafka.Header {
+	return e.headers
+}
+
+func encodingHeaders(contentType string, desc protoreflect.MessageDescriptor, extra ...kafka.Header) []kafka.Header {
+	headers := []kafka.Header{
+		{Key: "content-type", Value: []byte(contentType)},
+		{Key: "schema", Value: []byte(desc.FullName())},
+	}
+	return append(headers, extra...)
+}
+
+// newEventEncoder builds the encoder for messages of type desc. The encoding
+// comes from ENRICHED_ENCODING (protobuf, json or avro).
+func newEventEncoder(desc protoreflect.MessageDescriptor) (eventEncoder, error) {
+	encoding := strings.ToLower(os.Getenv("ENRICHED_ENCODING"))
+	if encoding == "" {
+		encoding = defaultEnrichedEncoding
+	}
+
+	switch encoding {
+	case encodingProtobuf:
+		return &protobufEncoder{headers: encodingHeaders("application/x-protobuf", desc)}, nil
+	case encodingJSON:
+		return &jsonEncoder{
+			options: protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+			headers: encodingHeaders("application/json", desc),
+		}, nil
+	case encodingAvro:
+		return newAvroEncoder(desc)
+	}
+	return nil, fmt.Errorf("unknown ENRICHED_ENCODING %q (want protobuf, json or avro)", encoding)
+}
@@ -1,8 +1,8 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"encoding/json"
 	"log"
 	"os"
 	"os/signal"
@@ -22,27 +22,29 @@ const (
 
 func main() {
 
+	// Wall clock, or event time driven for replays (CLOCK_MODE=simulated)
+	setupClock()
+
 	// Kafka Consumer setup
 	kafkaAddr := os.Getenv("KAFKA_BROKER")
 	if kafkaAddr == "" {
 		kafkaAddr = "localhost:9092"
 	}
+	// A replay (CLOCK_MODE=simulated) runs under its own group so it reads the
+	// topic from the start without moving the live enricher's offsets
+	groupID := os.Getenv("KAFKA_CONSUMER_GROUP")
+	if groupID == "" {
+		groupID = "foo"
+	}
 	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
      "bootstrap.servers":    kafkaAddr,
-     "group.id":             "foo",
+     "group.id":             groupID,
      "auto.offset.reset":    "smallest",
 	"enable.auto.commit": "false"})
 	if err != nil {
 		fmt.Println(err)
 	}
 
-	// Subscribe to Raw transactions Kafka topic
-	kafkaConsumerTopics := os.Getenv("KAFKA_CONSUMER_TOPICS_ENRICHER")
-	if kafkaConsumerTopics == "" {
-		kafkaConsumerTopics = fromKafkaTopic
-	}
-	err = consumer.SubscribeTopics([]string {kafkaConsumerTopics}, nil)
-
 	// Kafka Producer Setup
 	kafkaAddr_new := os.Getenv("KAFKA_BROKER")
 	if kafkaAddr_new == "" {
@@ -53,6 +55,15 @@ func main() {
 		log.Fatalf("Failed to create producer: %v", err)
 	}
 	defer p.Close()
+
+	// Subscribe to Raw transactions Kafka topic
+	kafkaConsumerTopics := os.Getenv("KAFKA_CONSUMER_TOPICS_ENRICHER")
+	if kafkaConsumerTopics == "" {
+		kafkaConsumerTopics = fromKafkaTopic
+	}
+	// Event time watermarks per partition, reset on every rebalance
+	watermarks := newWatermarkTracker(p)
+	err = consumer.SubscribeTopics([]string {kafkaConsumerTopics}, watermarks.rebalance)
 	
 	// Process message
 	MIN_COMMIT_COUNT := 20
@@ -68,12 +79,64 @@ func main() {
 	time.Sleep(10 * time.Second)
 
 	// Initializing MaxMindDB
-	cityDb, asnDB, cleanUpDB, err := initialize_maxmindDB()
+	// Loading fraud rules and watching them for changes
+	rules, err := newRuleEngine(client, ctx)
+	if err != nil {
+		log.Fatalf("Failed to load rules: %v", err)
+	}
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	defer stopWatching()
+	go rules.watch(watchCtx)
+
+	alerts := newAlertPublisher(p, client)
+
+	// Decision policy (ALLOW / REVIEW / BLOCK) applied after rule evaluation
+	decisionEngine, err := newDecisionEngine()
+	if err != nil {
+		log.Fatalf("Failed to load decision policy: %v", err)
+	}
+	go decisionEngine.watch(watchCtx)
+	decisions := newDecisionPublisher(p)
+
+	// Multi-step sequence patterns (state in Redis)
+	patterns, err := newCEPEngine(client)
 	if err != nil {
-		fmt.Println("WARNING: SOME ISSUE WITH THE DB CONNECTIONS... RESOLVE THIS ASAP")
+		log.Fatalf("Failed to load sequence patterns: %v", err)
 	}
+	go patterns.watch(watchCtx)
 
-	defer cleanUpDB()
+	// Geo provider (MaxMind by default). Enrichment carries on with empty geo
+	// fields and geo_status=UNAVAILABLE while it has no data.
+	geoProvider, cleanUpGeo, err := newGeoProvider(watchCtx)
+	if err != nil {
+		log.Fatalf("Failed to set up geo provider: %v", err)
+	}
+	defer func() {
+		stopWatching()
+		cleanUpGeo()
+	}()
+
+	geo := newGeoCache(client, geoProvider)
+
+	// network_type by ASN (cloud, hosting, cdn, mobile, residential, ...)
+	networks := newNetworkClassifier()
+	go networks.watch(watchCtx)
+
+	// Fraud model scoring from the model registry (unscored events if no model
+	// is promoted); promotions and challengers are picked up without a restart
+	scorer := newModelScorer()
+	go scorer.watch(watchCtx)
+
+	// Tor / VPN / proxy flags from the Anonymous IP database and local lists
+	reputation := newReputationChecker()
+	go reputation.watch(watchCtx)
+	startMetricsServer()
+
+	// Output encoding for enriched transactions
+	encoder, err := newEventEncoder((&pb.EnrichedTransaction{}).ProtoReflect().Descriptor())
+	if err != nil {
+		log.Fatalf("Failed to set up enriched transaction encoding: %v", err)
+	}
 
 	for run == true {
 		select {
@@ -103,61 +166,126 @@ func main() {
 						// Push to DLQ (to do later)
 						continue;
 					}
-					log.Printf("Transaction values: IP Address=%s, Txn Id=%s, UserId=%d, Amount=%.2f", txn.IpAddress, txn.TransactionId, txn.UserId, txn.Amount)
+					log.Printf("Transaction values: IP Address=%s, Txn Id=%s, UserId=%s, Amount=%.2f", txn.IpAddress, txn.TransactionId, txn.UserId, txn.Amount)
+
+					// EVENT TIME (windows it belongs to already closed -> late topic, not enriched)
+					txnTime := eventTime(&txn, e)
+					watermark, late := watermarks.observe(e.TopicPartition, txnTime)
+					if late {
+						log.Printf("Late txn %s: event time %s is %s behind the watermark of %s, routing to %s",
+							txn.TransactionId, txnTime.Format(time.RFC3339), watermark.Sub(txnTime), partitionKey(e.TopicPartition), watermarks.lateTopic)
+						if err := watermarks.publishLate(e, txnTime, watermark); err != nil {
+							log.Printf("Late event publish failed for txn %s: %v", txn.TransactionId, err)
+						}
+						continue
+					}
+					// The watermark rather than the raw timestamp, so it is capped like the watermark
+					clock.Observe(watermark)
 					
 					// Check if IP address is valid or not. If not valid push to DLQ and continue
 					var is_valid_ip bool = validateIP(txn.IpAddress)
 					
 					// If valid, Check whether we have that entry in Redis few mins back, else add it
 					if is_valid_ip {
-						var geoData *GeoData
-						// GEOIP ENRICHEMENT
-						data, status, err := getGeoFromRedis(client, ctx, txn.IpAddress)
+						// Every spelling of an address (IPv4-mapped, upper case, uncompressed IPv6) shares the same state
+						txn.IpAddress = normalizeIP(txn.IpAddress)
+
+						// IP CLASSIFICATION (private, reserved, CGNAT, ... never reach MaxMind)
+						ipClass, _ := classifyIP(txn.IpAddress)
+						if !isRoutableClass(ipClass) {
+							log.Printf("Non-routable IP %s (%s) on txn %s", txn.IpAddress, ipClass, txn.TransactionId)
+						}
+
+						// GEOIP ENRICHEMENT (in-process LRU -> Redis -> MaxMind)
+						geoData, tier, err := geo.Get(ctx, txn.IpAddress)
+						geoStatus := geoStatusFor(geoData, tier, err)
 						if err != nil {
-							fmt.Println("WARNING: SOMETHING IS WRONG WITH THE GET GEO FUNCTION...")
-							continue
+							geoErrorLog.Printf("lookup", "Geo lookup failed, continuing without geo: %v", err)
+							geoData = &GeoData{}
 						} else {
-							if status == "MISS" {
-							// Not present in cache, so check maxmind and update cache
-							cityRecord, asnRecord := maxMindDBLookup(txn.IpAddress, cityDb, asnDB)
-							geoData = &GeoData{
-								City:        cityRecord.City.Names["en"],
-								Country:     cityRecord.Country.Names["en"],
-								CountryCode: cityRecord.Country.IsoCode,
-								Latitude:    cityRecord.Location.Latitude,
-								Longitude:   cityRecord.Location.Longitude,
-								ASN:         fmt.Sprintf("AS%d", asnRecord.AutonomousSystemNumber),
-								ISP:         asnRecord.AutonomousSystemOrganization,
-								IsHosting:   isHostingProvider(asnRecord.AutonomousSystemOrganization),
-							}
-							// Update cache
-							status, err := setGeoToRedis(client, ctx, txn.IpAddress, *geoData)
-							if err != nil {
-								fmt.Println("SOME ISSUE WITH THE REDIS SET.......")
-							} else {
-								if status == "REDIS_SET_SUCCESS" {
-									fmt.Println("REDIS SET SUCCESSFUL...........")
-								}
-							}
-							
-						} else if status == "HIT" {
-							geoData = data
-							fmt.Printf("FOUND IN REDIS CACHE....... sample data: city = %s", geoData.City)
+							log.Printf("Geo for %s served from %s (city = %s)", txn.IpAddress, tier, geoData.City)
 						}
-						
+
 						// FRAUD METRICS ENRICHMENT
-						fraudData, err := updateFraudInRedis(client, ctx, txn.IpAddress, float64(txn.Amount))
+						fraudData, err := updateFraudInRedis(client, ctx, txn.IpAddress, float64(txn.Amount), txnTime)
 						if err != nil {
 							log.Printf("Fraud update failed for IP %s: %v", txn.IpAddress, err)
 							continue
 						}
 
-						log.Printf("ENRICHED_TXN ip=%s city=%s country=%s isp=%s hosting=%t txn_count_2h=%d total_2h=%.2f velocity=%.2f avg=%.2f max=%.2f",
+						// IPv6 velocity is also tracked for the whole prefix, so rotating addresses inside it doesn't reset it
+						var ipPrefix string
+						var prefixData *FraudSignals
+						if prefix, ok := aggregationPrefix(txn.IpAddress); ok {
+							ipPrefix = prefix.String()
+							prefixData, err = updateFraudInRedis(client, ctx, ipPrefix, float64(txn.Amount), txnTime)
+							if err != nil {
+								log.Printf("Fraud update failed for prefix %s: %v", ipPrefix, err)
+								continue
+							}
+						}
+
+						// SUBNET / ASN AGGREGATES (rings rotating IPs inside one network)
+						var subnetData, asnData *AggregateSignals
+						if subnet, ok := ipv4Subnet(txn.IpAddress); ok {
+							subnetData, err = updateAggregate(client, ctx, "subnet", subnet.String(), txn.UserId, float64(txn.Amount), txnTime)
+							if err != nil {
+								log.Printf("Subnet aggregate update failed for %s: %v", subnet, err)
+							}
+						}
+						if geoData.ASN != "" && geoData.ASN != "AS0" {
+							asnData, err = updateAggregate(client, ctx, "asn", geoData.ASN, txn.UserId, float64(txn.Amount), txnTime)
+							if err != nil {
+								log.Printf("ASN aggregate update failed for %s: %v", geoData.ASN, err)
+							}
+						}
+
+						// CARD TESTING (bursts of small transactions, then a large one)
+						cardTesting := make(map[string]*pb.CardTestingSignals)
+						cardTestingScopes := map[string]string{"ip": txn.IpAddress, "user": txn.UserId}
+						if subnet, ok := ipv4Subnet(txn.IpAddress); ok {
+							cardTestingScopes["subnet"] = subnet.String()
+						} else if ipPrefix != "" {
+							cardTestingScopes["subnet"] = ipPrefix
+						}
+						for scope, id := range cardTestingScopes {
+							if id == "" {
+								continue
+							}
+							cardTesting[scope], err = updateCardTesting(client, ctx, scope, id, txn.TransactionId, float64(txn.Amount), txnTime)
+							if err != nil {
+								log.Printf("Card testing update failed for %s %s: %v", scope, id, err)
+							}
+						}
+
+						// STRUCTURING (amounts kept just below reporting thresholds, AML)
+						userStructuring, err := updateStructuring(client, ctx, "user", txn.UserId, txn.TransactionId, float64(txn.Amount), txnTime)
+						if err != nil {
+							log.Printf("Structuring update failed for user %s: %v", txn.UserId, err)
+						}
+						var destStructuring *pb.StructuringSignals
+						if txn.DestAccount != "" {
+							destStructuring, err = updateStructuring(client, ctx, "dest", txn.DestAccount, txn.TransactionId, float64(txn.Amount), txnTime)
+							if err != nil {
+								log.Printf("Structuring update failed for destination %s: %v", txn.DestAccount, err)
+							}
+						}
+
+						// USER HISTORY (countries the user has transacted from)
+						userData, err := updateUserCountries(client, ctx, txn.UserId, geoData.CountryCode, txnTime)
+						if err != nil {
+							log.Printf("User history update failed for user %s: %v", txn.UserId, err)
+						}
+
+						// Classified per transaction rather than cached with the geo data, so category file changes apply at once
+						networkType := networks.Classify(geoData.ASN, geoData.ISP)
+
+						log.Printf("ENRICHED_TXN ip=%s city=%s country=%s isp=%s network_type=%s txn_count_2h=%d total_2h=%.2f velocity=%.2f avg=%.2f max=%.2f",
 							txn.IpAddress, 
 							geoData.City,
 							geoData.Country,
 							geoData.ISP,
-							geoData.IsHosting,
+							networkType,
 							fraudData.TxnCount,
 							fraudData.TotalAmount,
 							fraudData.AmountVelocity,
@@ -166,56 +294,94 @@ func main() {
 						)
 
 
-						// Few suspicious patterns
-						if fraudData.AmountVelocity > 50000 {
-							log.Printf("HIGH VELOCITY ALERT: IP %s spending $%.2f/hour!", 
-								txn.IpAddress, fraudData.AmountVelocity)
+						// BUILD ENRICHED EVENT
+						geoProto := geoData.toProto()
+						geoProto.NetworkType = networkType
+						geoProto.IsHosting = isDatacenterType(networkType)
+						enrichedTxn := &pb.EnrichedTransaction{
+							Transaction: &txn,
+							Geo:         geoProto,
+							IpSignals:   fraudData.toProto(),
+							IpClass:     ipClass,
+							GeoStatus:   geoStatus,
+							Anonymity:   reputation.Check(txn.IpAddress),
+							Balance:     balanceSignals(&txn),
+							UserSignals: userData,
+							EventTime:   txnTime.UnixMilli(),
+							Watermark:   watermark.UnixMilli(),
+
+							IpCardTesting:     cardTesting["ip"],
+							SubnetCardTesting: cardTesting["subnet"],
+							UserCardTesting:   cardTesting["user"],
+							UserStructuring:   userStructuring,
+							DestStructuring:   destStructuring,
+						}
+						if prefixData != nil {
+							enrichedTxn.IpPrefix = ipPrefix
+							enrichedTxn.PrefixSignals = prefixData.toProto()
+						}
+						if subnetData != nil {
+							enrichedTxn.SubnetSignals = subnetData.toProto()
+						}
+						if asnData != nil {
+							enrichedTxn.AsnSignals = asnData.toProto()
+						}
+
+						// MODEL SCORING (score is also a rule feature)
+						features := enrichedFeatures(enrichedTxn)
+						if result, ok := scorer.Score(features); ok {
+							enrichedTxn.FraudScore = result.Score
+							enrichedTxn.ModelVersion = result.Version
+							enrichedTxn.ChallengerScores = result.Challengers
+							enrichedTxn.ModelContributions = result.Contributions
+							enrichedTxn.ModelBaseValue = result.BaseValue
+							features["fraud_score"] = result.Score
+							features["model_version"] = result.Version
 						}
 
-						if fraudData.TxnCount > 20 {
-							log.Printf("HIGH FREQUENCY ALERT: IP %s made %d transactions in 2h!", 
-								txn.IpAddress, fraudData.TxnCount)
+						// RULE EVALUATION
+						ruleSet := rules.Rules()
+						enrichedTxn.RuleSetVersion = ruleSet.Version
+						enrichedTxn.RuleHits, enrichedTxn.ShadowRuleHits = ruleSet.Evaluate(features)
+
+						// SEQUENCE PATTERNS (completed sequences are rule hits too)
+						patternHits, shadowPatternHits := patterns.Process(ctx, txn.TransactionId, features, txnTime)
+						enrichedTxn.RuleHits = append(enrichedTxn.RuleHits, patternHits...)
+						enrichedTxn.ShadowRuleHits = append(enrichedTxn.ShadowRuleHits, shadowPatternHits...)
+						for _, hit := range enrichedTxn.RuleHits {
+							log.Printf("RULE HIT [%s/%s] %s: IP %s txn %s (action=%s)",
+								hit.Severity, hit.ReasonCode, hit.RuleId, txn.IpAddress, txn.TransactionId, hit.Action)
+							if hit.Action == "none" {
+								continue
+							}
+							status, err := alerts.publish(ctx, enrichedTxn, hit, features)
+							if err != nil {
+								log.Printf("Alert publish failed for rule %s (%s): %v", hit.RuleId, status, err)
+							} else if status == "SUPPRESSED" {
+								log.Printf("Alert for rule %s on IP %s / user %s suppressed (cooldown)", hit.RuleId, txn.IpAddress, txn.UserId)
+							}
+						}
+						for _, hit := range enrichedTxn.ShadowRuleHits {
+							log.Printf("SHADOW RULE HIT [%s/%s] %s: IP %s txn %s (rule set %s)",
+								hit.Severity, hit.ReasonCode, hit.RuleId, txn.IpAddress, txn.TransactionId, ruleSet.Version)
 						}
 
-						if fraudData.TotalAmount > 100000 {
-							log.Printf("HIGH AMOUNT ALERT: IP %s spent $%.2f in 2h!", 
-								txn.IpAddress, fraudData.TotalAmount)
+						// DECISION (one risk verdict per transaction)
+						policy := decisionEngine.Policy()
+						verdict := policy.Decide(enrichedTxn, features)
+						decision, err := decisions.publish(enrichedTxn, policy, verdict)
+						enrichedTxn.Decision = decision
+						if err != nil {
+							log.Printf("Decision publish failed for txn %s: %v", txn.TransactionId, err)
+						} else {
+							log.Printf("DECISION %s txn %s risk=%.4f reasons=%v (policy %s)",
+								verdict.Outcome, txn.TransactionId, verdict.RiskScore, verdict.ReasonCodes, policy.Version)
 						}
 
 						// PUSH TO KAFKA AS PRODUCER
-						enrichedTxn := EnrichedTransaction{
-							TransactionID: txn.TransactionId,
-							UserID: txn.UserId,
-							Amount: float64(txn.Amount),
-							Timestamp: txn.Timestamp,
-							IsFraud: txn.IsFraud,
-							Type: txn.Type,
-							OldBalanceOrig: float64(txn.OldBalanceOrig),
-							NewBalanceOrig: float64(txn.NewBalanceOrig),
-							OldBalanceDest: float64(txn.OldBalanceDest),
-							NewBalanceDest: float64(txn.NewBalanceDest),
-							IsUnauthorizedOverdraft: float64(txn.IsUnauthorizedOverdraft),
-							
-							IPAddress: txn.IpAddress,
-							City: geoData.City,
-							Country: geoData.Country,
-							CountryCode: geoData.CountryCode,
-							Latitude: geoData.Latitude,
-							Longitude: geoData.Longitude,
-							ASN: geoData.ASN,
-							ISP: geoData.ISP,
-							IsHosting: geoData.IsHosting,
-							
-							TxnCount2h: fraudData.TxnCount,
-							TotalAmount2h: fraudData.TotalAmount,
-							AmountVelocity: fraudData.AmountVelocity,
-							AvgAmount2h: fraudData.AvgAmount,
-							MaxAmount2h: fraudData.MaxAmount,
-						}
-
-						enrichedJSON, err := json.Marshal(enrichedTxn)
+						enrichedValue, err := encoder.Encode(enrichedTxn)
 						if err != nil {
-							log.Printf("JSON marshal failed: %v", err)
+							log.Printf("Encoding enriched transaction failed: %v", err)
 							continue
 						}
 
@@ -224,7 +390,8 @@ func main() {
 								Topic: &[]string{toKafkaTopic}[0],
 								Partition: kafka.PartitionAny,
 							},
-							Value: enrichedJSON,
+							Value:   enrichedValue,
+							Headers: encoder.Headers(),
 						}, nil)
 
 						if err != nil {
@@ -233,8 +400,6 @@ func main() {
 							log.Printf("Published Enriched transaction to %s", toKafkaTopic)
 						}
 
-					}
-
 					} else {
 						// Push to DLQ
 					}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+
+	pb "fraud-enricher/pb"
+)
+
+// Windowed features (fraud signals, subnet/ASN aggregates) are computed in
+// event time: the transaction's own timestamp (unix millis), falling back to
+// the Kafka message timestamp when the producer didn't set one. Replays,
+// backfills and delayed messages then produce the same features as the live
+// stream did.
+//
+// Each assigned partition has a watermark, the latest event time seen on it.
+// An event more than ALLOWED_LATENESS_SECONDS behind its partition's
+// watermark is not enriched, since the windows it belongs to have moved on.
+// It is forwarded unchanged to KAFKA_LATE_TOPIC (default late_transactions)
+// with headers saying where it came from and how late it was. Watermarks only
+// move forward, and never more than watermarkMaxFuture past the clock,
+// so one bad producer clock can't make the rest of a partition late. They
+// are reset when partitions are assigned or revoked, so messages re-read after
+// a rebalance aren't mistaken for late ones.
+const (
+	lateKafkaTopic         = "late_transactions"
+	defaultAllowedLateness = 5 * time.Minute
+	watermarkMaxFuture     = 5 * time.Minute
+)
+
+type watermarkTracker struct {
+	mu         sync.Mutex
+	watermarks map[string]time.Time // "<topic>/<partition>" -> watermark
+
+	allowedLateness time.Duration
+	producer        *kafka.Producer
+	lateTopic       string
+}
+
+func newWatermarkTracker(producer *kafka.Producer) *watermarkTracker {
+	w := &watermarkTracker{
+		watermarks:      make(map[string]time.Time),
+		allowedLateness: defaultAllowedLateness,
+		producer:        producer,
+		lateTopic:       os.Getenv("KAFKA_LATE_TOPIC"),
+	}
+	if env := os.Getenv("ALLOWED_LATENESS_SECONDS"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed >= 0 {
+			w.allowedLateness = time.Duration(parsed) * time.Second
+		}
+	}
+	if w.lateTopic == "" {
+		w.lateTopic = lateKafkaTopic
+	}
+	eventTimeMetrics.Set("allowed_lateness_seconds", intVar(int64(w.allowedLateness/time.Second)))
+	eventTimeMetrics.Set("watermarks", expvar.Func(w.snapshot))
+	return w
+}
+
+func partitionKey(tp kafka.TopicPartition) string {
+	topic := ""
+	if tp.Topic != nil {
+		topic = *tp.Topic
+	}
+	return topic + "/" + strconv.Itoa(int(tp.Partition))
+}
+
+// eventTime returns when the transaction happened
+func eventTime(txn *pb.TransactionRequest, msg *kafka.Message) time.Time {
+	if txn.Timestamp > 0 {
+		return time.UnixMilli(txn.Timestamp)
+	}
+	eventTimeMetrics.Add("timestamp_fallbacks", 1)
+	if !msg.Timestamp.IsZero() && msg.TimestampType != kafka.TimestampNotAvailable {
+		return msg.Timestamp
+	}
+	return clock.Now()
+}
+
+// observe checks the event against its partition's watermark and advances
+// the watermark. It returns the partition's watermark after the event and
+// whether the event is beyond the allowed lateness.
+func (w *watermarkTracker) observe(tp kafka.TopicPartition, at time.Time) (time.Time, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	key := partitionKey(tp)
+	watermark := w.watermarks[key]
+	if !watermark.IsZero() && at.Before(watermark.Add(-w.allowedLateness)) {
+		return watermark, true
+	}
+	advanced := at
+	// A simulated clock that hasn't observed anything yet has no time to cap by
+	if now := clock.Now(); now.UnixNano() > 0 {
+		if limit := now.Add(watermarkMaxFuture); advanced.After(limit) {
+			advanced = limit
+		}
+	}
+	if advanced.After(watermark) {
+		watermark = advanced
+		w.watermarks[key] = watermark
+	}
+	return watermark, false
+}
+
+// rebalance is the consumer's rebalance callback. It only forgets watermarks;
+// the client performs the assignment itself.
+func (w *watermarkTracker) rebalance(c *kafka.Consumer, ev kafka.Event) error {
+	var partitions []kafka.TopicPartition
+	switch e := ev.(type) {
+	case kafka.AssignedPartitions:
+		partitions = e.Partitions
+	case kafka.RevokedPartitions:
+		partitions = e.Partitions
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, tp := range partitions {
+		delete(w.watermarks, partitionKey(tp))
+	}
+	log.Printf("Rebalance (%v): reset watermarks for %d partitions", ev, len(partitions))
+	return nil
+}
+
+func (w *watermarkTracker) snapshot() interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make(map[string]int64, len(w.watermarks))
+	for key, watermark := range w.watermarks {
+		out[key] = watermark.UnixMilli()
+	}
+	return out
+}
+
+// publishLate forwards a late message as is to the late topic
+func (w *watermarkTracker) publishLate(msg *kafka.Message, at time.Time, watermark time.Time) error {
+	eventTimeMetrics.Add("late_events", 1)
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: "source-partition", Value: []byte(partitionKey(msg.TopicPartition))},
+		kafka.Header{Key: "source-offset", Value: []byte(msg.TopicPartition.Offset.String())},
+		kafka.Header{Key: "event-time", Value: []byte(strconv.FormatInt(at.UnixMilli(), 10))},
+		kafka.Header{Key: "watermark", Value: []byte(strconv.FormatInt(watermark.UnixMilli(), 10))},
+		kafka.Header{Key: "lateness-ms", Value: []byte(strconv.FormatInt(watermark.Sub(at).Milliseconds(), 10))},
+	)
+	err := w.producer.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{
+			Topic:     &w.lateTopic,
+			Partition: kafka.PartitionAny,
+		},
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}, nil)
+	if err != nil {
+		eventTimeMetrics.Add("late_publish_errors", 1)
+	}
+	return err
+}
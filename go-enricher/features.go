@@ -0,0 +1,64 @@
+package main
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// enrichedFeatures flattens a message into a map keyed by dotted proto field
+// path, e.g. "transaction.amount", "geo.country_code", "ip_signals.txn_count".
+// Every scalar field is present, including zero values, so rules never trip
+// over a field that happens to be unset. Numbers become float64 and enums
+// their value name; repeated and map fields are skipped.
+//
+// Fields in excludedFeatures are never flattened, so rules, alert snapshots
+// and models can't read them.
+func enrichedFeatures(msg proto.Message) map[string]interface{} {
+	features := make(map[string]interface{})
+	flattenMessage(msg.ProtoReflect(), "", features)
+	return features
+}
+
+// excludedFeatures are fields that must not act as features:
+// transaction.is_fraud is the ground-truth label of the simulated data.
+var excludedFeatures = map[string]bool{
+	"transaction.is_fraud": true,
+}
+
+func flattenMessage(m protoreflect.Message, prefix string, features map[string]interface{}) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.IsList() || fd.IsMap() {
+			continue
+		}
+		name := prefix + string(fd.Name())
+		if excludedFeatures[name] {
+			continue
+		}
+		value := m.Get(fd)
+
+		switch fd.Kind() {
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			flattenMessage(value.Message(), name+".", features)
+		case protoreflect.BoolKind:
+			features[name] = value.Bool()
+		case protoreflect.StringKind:
+			features[name] = value.String()
+		case protoreflect.BytesKind:
+			features[name] = string(value.Bytes())
+		case protoreflect.EnumKind:
+			if ev := fd.Enum().Values().ByNumber(value.Enum()); ev != nil {
+				features[name] = string(ev.Name())
+			} else {
+				features[name] = ""
+			}
+		case protoreflect.FloatKind, protoreflect.DoubleKind:
+			features[name] = value.Float()
+		case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+			features[name] = float64(value.Uint())
+		default:
+			features[name] = float64(value.Int())
+		}
+	}
+}
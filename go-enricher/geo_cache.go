@@ -0,0 +1,379 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultGeoLRUSize = 10000
+	defaultGeoLRUTTL  = 5 * time.Minute
+
+	// How often the known network prefix lengths are re-read from Redis, to pick
+	// up lengths cached by other enricher instances
+	geoPrefixRefreshInterval = time.Minute
+
+	// Geo errors are logged at most once per kind in this interval; the
+	// geo_cache counters keep the full count
+	geoErrorLogInterval = 10 * time.Second
+)
+
+// geoErrorLog logs the failures of the geo lookup path, which would otherwise
+// print a line per transaction while Redis or the provider is down
+var geoErrorLog = &rateLimitedLog{interval: geoErrorLogInterval}
+
+// rateLimitedLog writes at most one log line per key per interval and reports
+// how many were suppressed in between
+type rateLimitedLog struct {
+	interval time.Duration
+
+	mu         sync.Mutex
+	last       map[string]time.Time
+	suppressed map[string]int
+}
+
+func (l *rateLimitedLog) Printf(key string, format string, args ...interface{}) {
+	l.mu.Lock()
+	now := time.Now()
+	if last, ok := l.last[key]; ok && now.Sub(last) < l.interval {
+		l.suppressed[key]++
+		l.mu.Unlock()
+		return
+	}
+	if l.last == nil {
+		l.last, l.suppressed = make(map[string]time.Time), make(map[string]int)
+	}
+	suppressed := l.suppressed[key]
+	l.last[key], l.suppressed[key] = now, 0
+	l.mu.Unlock()
+
+	msg := fmt.Sprintf(format, args...)
+	if suppressed > 0 {
+		msg += fmt.Sprintf(" (%d similar suppressed)", suppressed)
+	}
+	log.Print(msg)
+}
+
+// lruCache is a bounded, TTL aware LRU safe for concurrent use
+type lruCache[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[string]*list.Element
+	order    *list.List // front = most recently used
+
+	onEvict func(expired bool)
+	// The TTL bounds how stale cached data can get while the process runs,
+	// so it is wall time even when the enricher clock is simulated
+	now func() time.Time
+}
+
+type lruEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+func newLRUCache[V any](capacity int, ttl time.Duration) *lruCache[V] {
+	return &lruCache[V]{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (c *lruCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*lruEntry[V])
+	if c.ttl > 0 && c.now().After(entry.expiresAt) {
+		c.removeElement(elem, true)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *lruCache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry[V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value, expiresAt: expiresAt})
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back(), false)
+	}
+}
+
+func (c *lruCache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *lruCache[V]) removeElement(elem *list.Element, expired bool) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry[V]).key)
+	if c.onEvict != nil {
+		c.onEvict(expired)
+	}
+}
+
+// geoCache is the two tier geo lookup: in-process LRU (L1) in front of Redis (L2),
+// falling back to the geo database on a miss in both. Concurrent misses for the
+// same IP share one Redis/database round trip.
+//
+// L2 entries are keyed by the network the geo database returned for the IP, so
+// one lookup serves every address in that range. prefixLens holds the prefix
+// lengths present in L2 per family (descending), which bounds how many
+// candidate networks a lookup has to probe.
+//
+// Both tiers key entries by the source's data version as well, so a geo
+// database reload takes effect at once instead of after the cache TTLs; the
+// previous version's entries just age out. prefixLens is tracked for
+// prefixVersion only.
+//
+// Without a Redis client (tests, benchmarks) there is no L2: L1 misses go
+// straight to the geo database.
+type geoCache struct {
+	l1     *lruCache[*GeoData]
+	client *redis.ClusterClient
+	group  singleflight.Group
+	source GeoProvider
+
+	prefixMu       sync.RWMutex
+	prefixVersion  string
+	prefixLens     map[string][]int // "4" / "6" -> prefix lengths, longest first
+	prefixLoadedAt time.Time
+}
+
+func newGeoCache(client *redis.ClusterClient, source GeoProvider) *geoCache {
+	size := defaultGeoLRUSize
+	if env := os.Getenv("GEO_LRU_SIZE"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			size = parsed
+		}
+	}
+	ttl := defaultGeoLRUTTL
+	if env := os.Getenv("GEO_LRU_TTL_SECONDS"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			ttl = time.Duration(parsed) * time.Second
+		}
+	}
+
+	l1 := newLRUCache[*GeoData](size, ttl)
+	l1.onEvict = func(expired bool) {
+		if expired {
+			geoCacheMetrics.Add("l1_expirations", 1)
+		} else {
+			geoCacheMetrics.Add("l1_evictions", 1)
+		}
+	}
+	geoCacheMetrics.Set("l1_size", expvar.Func(func() interface{} { return l1.Len() }))
+	geoCacheMetrics.Set("l2_network_lift", expvar.Func(geoNetworkLift))
+	return &geoCache{l1: l1, client: client, source: source, prefixLens: make(map[string][]int)}
+}
+
+// geoNetworkLift is the share of L2 hits that came from an entry seeded by a
+// different IP in the same network, i.e. hits a per-IP cache would have missed
+func geoNetworkLift() interface{} {
+	exact := counterValue(geoCacheMetrics, "l2_hits")
+	network := counterValue(geoCacheMetrics, "l2_network_hits")
+	if exact+network == 0 {
+		return 0.0
+	}
+	return float64(network) / float64(exact+network)
+}
+
+func counterValue(m *expvar.Map, key string) int64 {
+	if v, ok := m.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+// candidatePrefixLens returns the L2 prefix lengths of version to probe for ip
+func (c *geoCache) candidatePrefixLens(ctx context.Context, version string, ip string) []int {
+	family := "4"
+	if addr, err := netip.ParseAddr(ip); err == nil && addr.Unmap().Is6() {
+		family = "6"
+	}
+
+	c.prefixMu.RLock()
+	current := c.prefixVersion == version
+	stale := !current || time.Since(c.prefixLoadedAt) > geoPrefixRefreshInterval
+	var lens []int
+	if current {
+		lens = c.prefixLens[family]
+	}
+	c.prefixMu.RUnlock()
+	if !stale {
+		return lens
+	}
+
+	members, err := getGeoPrefixLengths(c.client, ctx, version)
+	if err != nil {
+		geoCacheMetrics.Add("prefix_len_errors", 1)
+		geoErrorLog.Printf("prefix_lens", "Loading geo prefix lengths failed: %v", err)
+		return lens
+	}
+	c.prefixMu.Lock()
+	defer c.prefixMu.Unlock()
+	if c.prefixVersion != version {
+		c.prefixVersion, c.prefixLens = version, make(map[string][]int)
+	}
+	c.prefixLoadedAt = time.Now()
+	for _, member := range members {
+		c.addPrefixLenLocked(member)
+	}
+	return c.prefixLens[family]
+}
+
+// addPrefixLenLocked records a "<family>/<bits>" member. Caller holds prefixMu.
+func (c *geoCache) addPrefixLenLocked(member string) {
+	family, bitsStr, ok := strings.Cut(member, "/")
+	bits, err := strconv.Atoi(bitsStr)
+	if !ok || err != nil {
+		return
+	}
+	lens := c.prefixLens[family]
+	for _, existing := range lens {
+		if existing == bits {
+			return
+		}
+	}
+	lens = append(lens, bits)
+	sort.Sort(sort.Reverse(sort.IntSlice(lens)))
+	c.prefixLens[family] = lens
+}
+
+func (c *geoCache) notePrefixLen(version string, network string, ip string) {
+	prefix, err := netip.ParsePrefix(network)
+	if err != nil {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return
+		}
+		addr = addr.Unmap()
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	c.prefixMu.Lock()
+	if c.prefixVersion == version {
+		c.addPrefixLenLocked(prefixLengthMember(prefix))
+	}
+	c.prefixMu.Unlock()
+}
+
+// Get returns the geo data for ip and the tier that served it: "L1", "L2",
+// "SOURCE" (the GeoProvider), or "NEGATIVE" for non-routable addresses. Those never reach Redis or
+// the geo database, which would only return an empty record that then got
+// cached as if it were real; they get an empty GeoData carrying the special
+// purpose range as its network.
+func (c *geoCache) Get(ctx context.Context, ip string) (*GeoData, string, error) {
+	if class, network := classifyIP(ip); !isRoutableClass(class) {
+		geoCacheMetrics.Add("negative_hits", 1)
+		return &GeoData{Network: network.String()}, "NEGATIVE", nil
+	}
+
+	// The version is read before the lookup: should a reload land in between,
+	// the newer answer ends up under the older key, never the reverse
+	version := c.source.Version()
+	key := version + "/" + ip
+
+	start := time.Now()
+	if geo, ok := c.l1.Get(key); ok {
+		geoCacheMetrics.Add("l1_hits", 1)
+		geoCacheMetrics.Add("l1_lookup_ns", time.Since(start).Nanoseconds())
+		return geo, "L1", nil
+	}
+	geoCacheMetrics.Add("l1_misses", 1)
+
+	type result struct {
+		geo  *GeoData
+		tier string
+	}
+	value, err, shared := c.group.Do(key, func() (interface{}, error) {
+		if c.client != nil {
+			if geo, ok := c.getL2(ctx, version, ip); ok {
+				c.l1.Set(key, geo)
+				return result{geo, "L2"}, nil
+			}
+		}
+
+		// Failures (including errGeoUnavailable) are returned uncached, so an
+		// outage never leaves empty records behind in L1/L2
+		geo, err := c.source.Lookup(ip)
+		if err != nil {
+			geoCacheMetrics.Add("source_errors", 1)
+			return nil, err
+		}
+		if c.client != nil {
+			if status, err := setGeoToRedis(c.client, ctx, version, ip, *geo); err != nil {
+				geoCacheMetrics.Add("l2_set_errors", 1)
+				geoErrorLog.Printf("l2_set", "Geo cache Redis set failed (%s): %v", status, err)
+			} else {
+				c.notePrefixLen(version, geo.Network, ip)
+			}
+		}
+		c.l1.Set(key, geo)
+		return result{geo, "SOURCE"}, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if shared {
+		geoCacheMetrics.Add("singleflight_shared", 1)
+	}
+	r := value.(result)
+	return r.geo, r.tier, nil
+}
+
+// getL2 looks ip up in the Redis entries of version. Redis trouble counts as a
+// miss, so it never stops enrichment.
+func (c *geoCache) getL2(ctx context.Context, version string, ip string) (*GeoData, bool) {
+	start := time.Now()
+	geo, status, err := getGeoFromRedis(c.client, ctx, version, ip, c.candidatePrefixLens(ctx, version, ip))
+	geoCacheMetrics.Add("l2_lookup_ns", time.Since(start).Nanoseconds())
+	if err != nil {
+		geoCacheMetrics.Add("l2_errors", 1)
+		geoErrorLog.Printf("l2_get", "Geo cache Redis get failed (%s): %v", status, err)
+	}
+	switch status {
+	case "HIT":
+		geoCacheMetrics.Add("l2_hits", 1)
+		return geo, true
+	case "NETWORK_HIT":
+		geoCacheMetrics.Add("l2_network_hits", 1)
+		return geo, true
+	}
+	geoCacheMetrics.Add("l2_misses", 1)
+	return nil, false
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// stubGeoProvider answers every lookup with a record of its current version
+// (as the ISP). With gate set, lookups block until it is closed.
+type stubGeoProvider struct {
+	calls   atomic.Int64
+	entered chan struct{}
+	gate    chan struct{}
+	version atomic.Value // string
+}
+
+func (p *stubGeoProvider) Lookup(ip string) (*GeoData, error) {
+	p.calls.Add(1)
+	if p.gate != nil {
+		select {
+		case p.entered <- struct{}{}:
+		default:
+		}
+		<-p.gate
+	}
+	return &GeoData{City: "Mountain View", CountryCode: "US", ASN: "AS15169", ISP: p.Version(), Network: ip + "/32"}, nil
+}
+
+func (p *stubGeoProvider) Name() string   { return "stub" }
+func (p *stubGeoProvider) Status() string { return geoProviderOK }
+func (p *stubGeoProvider) Version() string {
+	version, _ := p.version.Load().(string)
+	return version
+}
+
+func TestLRUCacheTTLExpiry(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	now := start
+	cache := newLRUCache[int](10, time.Minute)
+	cache.now = func() time.Time { return now }
+	var expirations int
+	cache.onEvict = func(expired bool) {
+		if expired {
+			expirations++
+		}
+	}
+	cache.Set("a", 1)
+
+	now = start.Add(time.Minute)
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get at the TTL = %v, %v; want 1, true", v, ok)
+	}
+	now = start.Add(time.Minute + time.Second)
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("Get after the TTL hit an expired entry")
+	}
+	if expirations != 1 || cache.Len() != 0 {
+		t.Fatalf("expirations = %d, len = %d; want 1, 0", expirations, cache.Len())
+	}
+
+	// Set refreshes the expiry
+	cache.Set("b", 2)
+	now = start.Add(90 * time.Second)
+	cache.Set("b", 3)
+	now = start.Add(2*time.Minute + time.Second)
+	if v, ok := cache.Get("b"); !ok || v != 3 {
+		t.Fatalf("Get after refresh = %v, %v; want 3, true", v, ok)
+	}
+}
+
+func TestLRUCacheEvictionOrder(t *testing.T) {
+	cache := newLRUCache[string](3, time.Hour)
+	var evicted []string
+	cache.onEvict = func(expired bool) {
+		if expired {
+			t.Error("capacity eviction reported as expiry")
+		}
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, key)
+	}
+	cache.Get("a")      // b is now least recently used
+	cache.Set("c", "c") // an update moves c to the front too
+	cache.Set("d", "d")
+	cache.Set("e", "e")
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		if _, ok := cache.Get(key); !ok {
+			evicted = append(evicted, key)
+		}
+	}
+	if got := strings.Join(evicted, ","); got != "a,b" {
+		t.Fatalf("evicted %s, want a,b", got)
+	}
+	if cache.Len() != 3 {
+		t.Fatalf("len = %d, want 3", cache.Len())
+	}
+}
+
+func TestGeoCacheSingleflight(t *testing.T) {
+	provider := &stubGeoProvider{entered: make(chan struct{}, 1), gate: make(chan struct{})}
+	cache := newGeoCache(nil, provider)
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]*GeoData, callers)
+	tiers := make([]string, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			geo, tier, err := cache.Get(context.Background(), "8.8.8.8")
+			if err != nil {
+				t.Error(err)
+			}
+			results[i], tiers[i] = geo, tier
+		}(i)
+	}
+	// Let the other callers queue up behind the first lookup
+	<-provider.entered
+	time.Sleep(50 * time.Millisecond)
+	close(provider.gate)
+	wg.Wait()
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("provider called %d times for %d concurrent misses, want 1", calls, callers)
+	}
+	for i := range results {
+		if results[i] != results[0] {
+			t.Fatalf("caller %d got a different record", i)
+		}
+		if tiers[i] != "SOURCE" && tiers[i] != "L1" {
+			t.Fatalf("caller %d served from %q", i, tiers[i])
+		}
+	}
+	if geo, tier, _ := cache.Get(context.Background(), "8.8.8.8"); tier != "L1" || geo != results[0] {
+		t.Fatalf("follow-up lookup served from %q, want L1", tier)
+	}
+}
+
+func TestRateLimitedLog(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	l := &rateLimitedLog{interval: 50 * time.Millisecond}
+	for i := 0; i < 5; i++ {
+		l.Printf("get", "get failed: %d", i)
+	}
+	l.Printf("set", "set failed")
+	time.Sleep(60 * time.Millisecond)
+	l.Printf("get", "get failed: again")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("logged %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	for i, want := range []string{"get failed: 0", "set failed", "get failed: again (4 similar suppressed)"} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("line %d = %q, want suffix %q", i, lines[i], want)
+		}
+	}
+}
+
+func TestGeoCacheVersionChange(t *testing.T) {
+	provider := &stubGeoProvider{}
+	provider.version.Store("1")
+	cache := newGeoCache(nil, provider)
+
+	if geo, tier, _ := cache.Get(context.Background(), "8.8.8.8"); tier != "SOURCE" || geo.ISP != "1" {
+		t.Fatalf("first lookup = %q from %s", geo.ISP, tier)
+	}
+	if _, tier, _ := cache.Get(context.Background(), "8.8.8.8"); tier != "L1" {
+		t.Fatalf("repeat lookup served from %s, want L1", tier)
+	}
+	// After a reload the entries of the old data must not be served
+	provider.version.Store("2")
+	if geo, tier, _ := cache.Get(context.Background(), "8.8.8.8"); tier != "SOURCE" || geo.ISP != "2" {
+		t.Fatalf("lookup after reload = %q from %s, want 2 from SOURCE", geo.ISP, tier)
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Fatalf("provider called %d times, want 2", calls)
+	}
+}
+
+// BenchmarkGeoCache compares the tiers: an L1 hit, an L1 miss answered by the
+// provider (no Redis), and an L1 miss answered by Redis. The L2 case needs a
+// Redis cluster, e.g. GEO_BENCH_REDIS_ADDRS=192.168.240.100:6379.
+func BenchmarkGeoCache(b *testing.B) {
+	ctx := context.Background()
+
+	b.Run("L1_hit", func(b *testing.B) {
+		cache := newGeoCache(nil, &stubGeoProvider{})
+		cache.Get(ctx, "8.8.8.8")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, tier, _ := cache.Get(ctx, "8.8.8.8"); tier != "L1" {
+				b.Fatalf("served from %s", tier)
+			}
+		}
+	})
+
+	b.Run("provider_miss", func(b *testing.B) {
+		cache := newGeoCache(nil, &stubGeoProvider{})
+		ips := benchmarkIPs(b.N)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, tier, _ := cache.Get(ctx, ips[i]); tier != "SOURCE" {
+				b.Fatalf("served from %s", tier)
+			}
+		}
+	})
+
+	b.Run("L2_hit", func(b *testing.B) {
+		addrs := os.Getenv("GEO_BENCH_REDIS_ADDRS")
+		if addrs == "" {
+			b.Skip("GEO_BENCH_REDIS_ADDRS not set")
+		}
+		client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: strings.Split(addrs, ",")})
+		defer client.Close()
+		cache := newGeoCache(client, &stubGeoProvider{})
+		ips := benchmarkIPs(b.N)
+		for _, ip := range ips {
+			if _, _, err := cache.Get(ctx, ip); err != nil {
+				b.Fatal(err)
+			}
+		}
+		// A fresh L1 so every lookup goes to Redis
+		cache.l1 = newLRUCache[*GeoData](len(ips), time.Minute)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, tier, _ := cache.Get(ctx, ips[i]); tier != "L2" {
+				b.Fatalf("served from %s", tier)
+			}
+		}
+	})
+}
+
+// benchmarkIPs returns n distinct public addresses
+func benchmarkIPs(n int) []string {
+	ips := make([]string, n)
+	for i := range ips {
+		ips[i] = fmt.Sprintf("8.%d.%d.%d", (i>>16)&0xff, (i>>8)&0xff, i&0xff)
+	}
+	return ips
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"os"
+)
+
+// GeoProvider resolves an IP to geo/ASN data. Implementations must be safe
+// for concurrent use.
+type GeoProvider interface {
+	// Lookup returns errGeoUnavailable while the provider has no data to serve
+	Lookup(ip string) (*GeoData, error)
+	Name() string
+	// Status is geoProviderOK or geoProviderUnavailable
+	Status() string
+	// Version identifies the data being served (e.g. the database build), so
+	// answers cached from an earlier version are never served after a reload
+	Version() string
+}
+
+const (
+	geoProviderOK          = "ok"
+	geoProviderUnavailable = "unavailable"
+)
+
+var errGeoUnavailable = errors.New("geo provider unavailable")
+
+// geo_status values on the enriched event
+const (
+	geoStatusOK          = "OK"
+	geoStatusNotFound    = "NOT_FOUND"    // provider has no record for the IP
+	geoStatusNonRoutable = "NON_ROUTABLE" // private/reserved/... never looked up
+	geoStatusUnavailable = "UNAVAILABLE"  // provider down, geo fields empty
+	geoStatusError       = "ERROR"
+)
+
+// nullGeoProvider has no data. It serves GEO_PROVIDER=none (e.g. running
+// without a MaxMind licence) so the pipeline still works with empty geo fields.
+type nullGeoProvider struct{}
+
+func (nullGeoProvider) Lookup(ip string) (*GeoData, error) { return nil, errGeoUnavailable }
+func (nullGeoProvider) Name() string                       { return "none" }
+func (nullGeoProvider) Status() string                     { return geoProviderUnavailable }
+func (nullGeoProvider) Version() string                    { return "" }
+
+// newGeoProvider builds the provider selected by GEO_PROVIDER: maxmind (default),
+// csv or none. A provider that cannot load yet is still returned: it reports
+// itself unavailable and recovers in the background once its data appears.
+func newGeoProvider(ctx context.Context) (GeoProvider, func(), error) {
+	name := os.Getenv("GEO_PROVIDER")
+	if name == "" {
+		name = "maxmind"
+	}
+
+	var provider GeoProvider
+	cleanup := func() {}
+	switch name {
+	case "maxmind":
+		dbs, cleanUpDB, err := initialize_maxmindDB()
+		if err != nil {
+			fmt.Printf("WARNING: SOME ISSUE WITH THE DB CONNECTIONS, GEO ENRICHMENT DEGRADED UNTIL THEY LOAD: %v\n", err)
+		}
+		go dbs.watch(ctx)
+		provider, cleanup = dbs, cleanUpDB
+	case "csv":
+		csvProvider, err := newCSVGeoProvider()
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading CSV geo data: %w", err)
+		}
+		provider = csvProvider
+	case "none":
+		provider = nullGeoProvider{}
+	default:
+		return nil, nil, fmt.Errorf("unknown GEO_PROVIDER %q (maxmind, csv, none)", name)
+	}
+
+	geoIPMetrics.Set("provider", stringVar(provider.Name()))
+	geoIPMetrics.Set("provider_status", expvar.Func(func() interface{} { return provider.Status() }))
+	geoIPMetrics.Set("data_version", expvar.Func(func() interface{} { return provider.Version() }))
+	return provider, cleanup, nil
+}
+
+// geoStatusFor maps a geoCache.Get outcome to the event's geo_status
+func geoStatusFor(geo *GeoData, tier string, err error) string {
+	switch {
+	case errors.Is(err, errGeoUnavailable):
+		return geoStatusUnavailable
+	case err != nil:
+		return geoStatusError
+	case tier == "NEGATIVE":
+		return geoStatusNonRoutable
+	case geo.CountryCode == "" && geo.City == "" && geo.ASN == "":
+		return geoStatusNotFound
+	}
+	return geoStatusOK
+}
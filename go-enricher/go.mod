@@ -4,14 +4,20 @@ go 1.25.5
 
 require (
 	github.com/confluentinc/confluent-kafka-go v1.9.2
+	github.com/linkedin/goavro/v2 v2.12.0
+	github.com/maxmind/mmdbwriter v1.0.0
 	github.com/oschwald/geoip2-golang v1.13.0
+	github.com/oschwald/maxminddb-golang v1.13.0
 	github.com/redis/go-redis/v9 v9.17.2
+	golang.org/x/sync v0.17.0
 	google.golang.org/protobuf v1.28.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
-	github.com/oschwald/maxminddb-golang v1.13.0 // indirect
+	github.com/golang/snappy v0.0.4 // indirect
+	go4.org/netipx v0.0.0-20220812043211-3cc044ffd68d // indirect
 	golang.org/x/sys v0.20.0 // indirect
 )
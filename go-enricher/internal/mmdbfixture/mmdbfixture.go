@@ -0,0 +1,192 @@
+// Package mmdbfixture builds small GeoLite2-compatible City and ASN .mmdb
+// files from a YAML fixture, so geo enrichment can be exercised against known
+// IPs without the real (licensed) databases. cmd/mmdbgen writes them to disk;
+// tests write them to a temporary directory.
+package mmdbfixture
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	CityFile = "GeoLite2-City.mmdb"
+	ASNFile  = "GeoLite2-ASN.mmdb"
+)
+
+// Fixture is the YAML input. A network without city/country data is left out
+// of the City database, one without an asn is left out of the ASN database.
+type Fixture struct {
+	BuildEpoch int64     `yaml:"build_epoch"`
+	Networks   []Network `yaml:"networks"`
+}
+
+// Network is one fixture network and its City / ASN records
+type Network struct {
+	Network      string  `yaml:"network"`
+	City         string  `yaml:"city"`
+	Country      string  `yaml:"country"`
+	CountryCode  string  `yaml:"country_code"`
+	Latitude     float64 `yaml:"latitude"`
+	Longitude    float64 `yaml:"longitude"`
+	ASN          uint32  `yaml:"asn"`
+	Organization string  `yaml:"organization"`
+}
+
+func (n Network) hasCity() bool {
+	return n.City != "" || n.Country != "" || n.CountryCode != ""
+}
+
+// Load reads a fixture. The build epoch is required so the output is
+// deterministic.
+func Load(path string) (Fixture, error) {
+	var fx Fixture
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fx, err
+	}
+	if err := yaml.Unmarshal(data, &fx); err != nil {
+		return fx, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	if fx.BuildEpoch == 0 {
+		return fx, fmt.Errorf("fixture %s needs a build_epoch so the output is deterministic", path)
+	}
+	return fx, nil
+}
+
+// Write writes the City and ASN databases for fx to dir, checks them with
+// Verify and returns their paths.
+func Write(dir string, fx Fixture) (cityPath, asnPath string, err error) {
+	cityPath = filepath.Join(dir, CityFile)
+	asnPath = filepath.Join(dir, ASNFile)
+	if err := writeDB(cityPath, "GeoLite2-City", fx, cityRecord); err != nil {
+		return "", "", fmt.Errorf("write %s: %w", cityPath, err)
+	}
+	if err := writeDB(asnPath, "GeoLite2-ASN", fx, asnRecord); err != nil {
+		return "", "", fmt.Errorf("write %s: %w", asnPath, err)
+	}
+	if err := Verify(cityPath, asnPath, fx); err != nil {
+		return "", "", fmt.Errorf("verify written databases: %w", err)
+	}
+	return cityPath, asnPath, nil
+}
+
+// cityRecord / asnRecord return the record for a network, or nil to leave the
+// network out of that database. Field names follow the GeoLite2 layout that
+// geoip2-golang decodes.
+func cityRecord(n Network) mmdbtype.DataType {
+	if !n.hasCity() {
+		return nil
+	}
+	return mmdbtype.Map{
+		"city": mmdbtype.Map{
+			"names": mmdbtype.Map{"en": mmdbtype.String(n.City)},
+		},
+		"country": mmdbtype.Map{
+			"iso_code": mmdbtype.String(n.CountryCode),
+			"names":    mmdbtype.Map{"en": mmdbtype.String(n.Country)},
+		},
+		"location": mmdbtype.Map{
+			"latitude":  mmdbtype.Float64(n.Latitude),
+			"longitude": mmdbtype.Float64(n.Longitude),
+		},
+	}
+}
+
+func asnRecord(n Network) mmdbtype.DataType {
+	if n.ASN == 0 {
+		return nil
+	}
+	return mmdbtype.Map{
+		"autonomous_system_number":       mmdbtype.Uint32(n.ASN),
+		"autonomous_system_organization": mmdbtype.String(n.Organization),
+	}
+}
+
+func writeDB(path, dbType string, fx Fixture, record func(Network) mmdbtype.DataType) error {
+	tree, err := mmdbwriter.New(mmdbwriter.Options{
+		BuildEpoch:   fx.BuildEpoch,
+		DatabaseType: dbType,
+		Description:  map[string]string{"en": "Synthetic " + dbType + " test database"},
+		Languages:    []string{"en"},
+		RecordSize:   24,
+		// Fixtures may use documentation / private ranges
+		IncludeReservedNetworks: true,
+	})
+	if err != nil {
+		return err
+	}
+	for _, n := range fx.Networks {
+		value := record(n)
+		if value == nil {
+			continue
+		}
+		_, network, err := net.ParseCIDR(n.Network)
+		if err != nil {
+			return fmt.Errorf("network %q: %w", n.Network, err)
+		}
+		if err := tree.Insert(network, value); err != nil {
+			return fmt.Errorf("insert %s: %w", n.Network, err)
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := tree.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Verify looks up the first address of every fixture network the same way the
+// enricher does and compares the decoded records with the fixture.
+func Verify(cityPath, asnPath string, fx Fixture) error {
+	cityDB, err := maxminddb.Open(cityPath)
+	if err != nil {
+		return err
+	}
+	defer cityDB.Close()
+	asnDB, err := maxminddb.Open(asnPath)
+	if err != nil {
+		return err
+	}
+	defer asnDB.Close()
+
+	for _, n := range fx.Networks {
+		ip, _, err := net.ParseCIDR(n.Network)
+		if err != nil {
+			return err
+		}
+		if n.hasCity() {
+			var city geoip2.City
+			if _, found, err := cityDB.LookupNetwork(ip, &city); err != nil || !found {
+				return fmt.Errorf("%s: city record not found (%v)", n.Network, err)
+			}
+			if city.City.Names["en"] != n.City || city.Country.IsoCode != n.CountryCode ||
+				city.Location.Latitude != n.Latitude || city.Location.Longitude != n.Longitude {
+				return fmt.Errorf("%s: city record %+v does not match fixture", n.Network, city)
+			}
+		}
+		if n.ASN != 0 {
+			var asn geoip2.ASN
+			if _, found, err := asnDB.LookupNetwork(ip, &asn); err != nil || !found {
+				return fmt.Errorf("%s: asn record not found (%v)", n.Network, err)
+			}
+			if asn.AutonomousSystemNumber != uint(n.ASN) || asn.AutonomousSystemOrganization != n.Organization {
+				return fmt.Errorf("%s: asn record %+v does not match fixture", n.Network, asn)
+			}
+		}
+	}
+	return nil
+}
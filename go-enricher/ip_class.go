@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/netip"
+)
+
+// IP classes stamped on the enriched event as ip_class. Everything other than
+// ipClassPublic is non-routable on the internet: it has no meaningful geo or
+// ASN data, and on a customer transaction it points to a spoofed header or an
+// internal caller.
+const (
+	ipClassPublic        = "public"
+	ipClassPrivate       = "private"
+	ipClassLoopback      = "loopback"
+	ipClassLinkLocal     = "link_local"
+	ipClassMulticast     = "multicast"
+	ipClassDocumentation = "documentation"
+	ipClassCGNAT         = "cgnat"
+	ipClassReserved      = "reserved" // bogons: unallocated / special purpose
+	ipClassUnspecified   = "unspecified"
+)
+
+type ipClassRange struct {
+	prefix netip.Prefix
+	class  string
+}
+
+// Special purpose ranges from the IANA IPv4/IPv6 special-purpose registries.
+// More specific ranges come first.
+var ipClassRanges = []ipClassRange{
+	// IPv4
+	{netip.MustParsePrefix("0.0.0.0/8"), ipClassReserved},
+	{netip.MustParsePrefix("10.0.0.0/8"), ipClassPrivate},
+	{netip.MustParsePrefix("100.64.0.0/10"), ipClassCGNAT},
+	{netip.MustParsePrefix("127.0.0.0/8"), ipClassLoopback},
+	{netip.MustParsePrefix("169.254.0.0/16"), ipClassLinkLocal},
+	{netip.MustParsePrefix("172.16.0.0/12"), ipClassPrivate},
+	{netip.MustParsePrefix("192.0.0.0/24"), ipClassReserved},
+	{netip.MustParsePrefix("192.0.2.0/24"), ipClassDocumentation},
+	{netip.MustParsePrefix("192.88.99.0/24"), ipClassReserved},
+	{netip.MustParsePrefix("192.168.0.0/16"), ipClassPrivate},
+	{netip.MustParsePrefix("198.18.0.0/15"), ipClassReserved},
+	{netip.MustParsePrefix("198.51.100.0/24"), ipClassDocumentation},
+	{netip.MustParsePrefix("203.0.113.0/24"), ipClassDocumentation},
+	{netip.MustParsePrefix("224.0.0.0/4"), ipClassMulticast},
+	{netip.MustParsePrefix("240.0.0.0/4"), ipClassReserved},
+
+	// IPv6
+	{netip.MustParsePrefix("::1/128"), ipClassLoopback},
+	{netip.MustParsePrefix("64:ff9b:1::/48"), ipClassReserved},
+	{netip.MustParsePrefix("100::/64"), ipClassReserved},
+	// 2001::/23 is the IETF protocol assignments block, but only these parts
+	// of it are not globally reachable (2001:1::1/128, 2001:3::/32, ORCHIDv2
+	// 2001:20::/28 and others are)
+	{netip.MustParsePrefix("2001::/32"), ipClassReserved}, // Teredo: the client's IPv4 address is obfuscated inside
+	{netip.MustParsePrefix("2001:2::/48"), ipClassReserved},
+	{netip.MustParsePrefix("2001:10::/28"), ipClassReserved},
+	{netip.MustParsePrefix("2001:db8::/32"), ipClassDocumentation},
+	{netip.MustParsePrefix("3fff::/20"), ipClassDocumentation},
+	{netip.MustParsePrefix("fc00::/7"), ipClassPrivate},
+	{netip.MustParsePrefix("fe80::/10"), ipClassLinkLocal},
+	{netip.MustParsePrefix("ff00::/8"), ipClassMulticast},
+}
+
+// Only 2000::/3 is allocated for global unicast; anything else left in IPv6 is a bogon
+var ipv6GlobalUnicast = netip.MustParsePrefix("2000::/3")
+
+// classifyIP returns the class of ip and, for non-public classes, the special
+// purpose range it falls in. IPv4-mapped IPv6 addresses are classified as IPv4.
+func classifyIP(ip string) (string, netip.Prefix) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ipClassReserved, netip.Prefix{}
+	}
+	addr = addr.Unmap()
+	if addr.IsUnspecified() {
+		return ipClassUnspecified, netip.PrefixFrom(addr, addr.BitLen())
+	}
+	for _, r := range ipClassRanges {
+		if r.prefix.Contains(addr) {
+			return r.class, r.prefix
+		}
+	}
+	if addr.Is6() && !ipv6GlobalUnicast.Contains(addr) {
+		return ipClassReserved, netip.PrefixFrom(addr, 3).Masked()
+	}
+	return ipClassPublic, netip.Prefix{}
+}
+
+func isRoutableClass(class string) bool {
+	return class == ipClassPublic
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestClassifyIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		class   string
+		network string // the special purpose range, "" for none
+	}{
+		{"8.8.8.8", ipClassPublic, ""},
+		{"10.1.2.3", ipClassPrivate, "10.0.0.0/8"},
+		{"::ffff:192.168.1.1", ipClassPrivate, "192.168.0.0/16"},
+		{"100.64.0.1", ipClassCGNAT, "100.64.0.0/10"},
+		{"198.51.100.7", ipClassDocumentation, "198.51.100.0/24"},
+		{"::1", ipClassLoopback, "::1/128"},
+		{"::", ipClassUnspecified, "::/128"},
+		{"2001:4860:4860::8888", ipClassPublic, ""},
+		// The non-global parts of 2001::/23
+		{"2001::1", ipClassReserved, "2001::/32"},
+		{"2001:0:4136:e378:8000:63bf:3fff:fdd2", ipClassReserved, "2001::/32"},
+		{"2001:2::1", ipClassReserved, "2001:2::/48"},
+		{"2001:10::1", ipClassReserved, "2001:10::/28"},
+		{"2001:1f:ffff::1", ipClassReserved, "2001:10::/28"},
+		{"2001:db8::1", ipClassDocumentation, "2001:db8::/32"},
+		// and the globally reachable rest of it
+		{"2001:1::1", ipClassPublic, ""},
+		{"2001:1::2", ipClassPublic, ""},
+		{"2001:2:1::1", ipClassPublic, ""},
+		{"2001:3::1", ipClassPublic, ""},
+		{"2001:4:112::1", ipClassPublic, ""},
+		{"2001:20::1", ipClassPublic, ""},
+		{"2001:1ff::1", ipClassPublic, ""},
+		// Outside 2000::/3
+		{"4000::1", ipClassReserved, "4000::/3"},
+		{"not an ip", ipClassReserved, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			class, network := classifyIP(tt.ip)
+			got := ""
+			if network.IsValid() {
+				got = network.String()
+			}
+			if class != tt.class || got != tt.network {
+				t.Errorf("classifyIP(%q) = %s, %q; want %s, %q", tt.ip, class, got, tt.class, tt.network)
+			}
+		})
+	}
+}
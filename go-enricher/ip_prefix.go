@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"net/netip"
+	"os"
+	"strconv"
+)
+
+// An IPv6 client usually controls a whole /64 (often a /48 or /56), so per
+// address velocity is trivial to evade by rotating the interface ID. IPv6
+// fraud state is therefore also aggregated per IPV6_AGG_PREFIX network.
+const defaultIPv6AggPrefix = 64
+
+var ipv6AggPrefix = loadIPv6AggPrefix()
+
+func loadIPv6AggPrefix() int {
+	bits := defaultIPv6AggPrefix
+	if env := os.Getenv("IPV6_AGG_PREFIX"); env != "" {
+		parsed, err := strconv.Atoi(env)
+		if err != nil || parsed < 1 || parsed > 128 {
+			log.Printf("Ignoring invalid IPV6_AGG_PREFIX %q, using /%d", env, bits)
+		} else {
+			bits = parsed
+		}
+	}
+	return bits
+}
+
+// normalizeIP returns the canonical text form of ip, so every spelling of an
+// address maps to the same Redis keys: IPv4-mapped IPv6 (::ffff:1.2.3.4)
+// becomes plain IPv4, IPv6 is lower-cased and zero-compressed (RFC 5952) and
+// any zone is dropped. Unparseable input is returned unchanged.
+func normalizeIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	return addr.Unmap().WithZone("").String()
+}
+
+// aggregationPrefix returns the IPV6_AGG_PREFIX network containing ip, or
+// false for IPv4 (including IPv4-mapped) addresses.
+func aggregationPrefix(ip string) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+	if !addr.Is6() {
+		return netip.Prefix{}, false
+	}
+	prefix, err := addr.Prefix(ipv6AggPrefix)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
+
+// ipv4Subnet returns the /24 containing ip, or false for IPv6
+func ipv4Subnet(ip string) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	if !addr.Is4() {
+		return netip.Prefix{}, false
+	}
+	prefix, err := addr.Prefix(24)
+	return prefix, err == nil
+}
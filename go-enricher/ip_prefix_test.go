@@ -0,0 +1,90 @@
+package main
+
+import "testing"
+
+func TestNormalizeIP(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		want string
+	}{
+		{"ipv4", "203.0.113.7", "203.0.113.7"},
+		{"ipv4 mapped", "::ffff:203.0.113.7", "203.0.113.7"},
+		{"ipv4 mapped hex", "::ffff:cb00:7107", "203.0.113.7"},
+		{"ipv6 upper case", "2001:DB8::1", "2001:db8::1"},
+		{"ipv6 uncompressed", "2001:0db8:0000:0000:0000:0000:0000:0001", "2001:db8::1"},
+		{"ipv6 zone", "fe80::1%eth0", "fe80::1"},
+		{"ipv4 mapped zone", "::ffff:10.0.0.1%eth0", "10.0.0.1"},
+		{"invalid", "not-an-ip", "not-an-ip"},
+		{"ipv4 with port", "203.0.113.7:443", "203.0.113.7:443"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeIP(tt.ip); got != tt.want {
+				t.Errorf("normalizeIP(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregationPrefix(t *testing.T) {
+	if ipv6AggPrefix != defaultIPv6AggPrefix {
+		t.Skipf("IPV6_AGG_PREFIX is set to /%d", ipv6AggPrefix)
+	}
+	tests := []struct {
+		name string
+		ip   string
+		want string // "" for no prefix
+	}{
+		{"ipv4", "203.0.113.7", ""},
+		{"ipv4 mapped", "::ffff:203.0.113.7", ""},
+		{"ipv6", "2001:db8:1:2:aaaa:bbbb:cccc:dddd", "2001:db8:1:2::/64"},
+		{"ipv6 same /64", "2001:db8:1:2::1", "2001:db8:1:2::/64"},
+		{"ipv6 upper case", "2001:DB8:1:2::1", "2001:db8:1:2::/64"},
+		{"ipv6 zone", "fe80::1:2:3:4%eth0", "fe80::/64"},
+		{"invalid", "2001:db8::zz", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, ok := aggregationPrefix(tt.ip)
+			got := ""
+			if ok {
+				got = prefix.String()
+			}
+			if got != tt.want {
+				t.Errorf("aggregationPrefix(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPv4Subnet(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		want string // "" for no subnet
+	}{
+		{"ipv4", "203.0.113.7", "203.0.113.0/24"},
+		{"ipv4 network address", "203.0.113.0", "203.0.113.0/24"},
+		{"ipv4 mapped", "::ffff:203.0.113.7", "203.0.113.0/24"},
+		{"ipv4 mapped zone", "::ffff:203.0.113.7%eth0", "203.0.113.0/24"},
+		{"ipv6", "2001:db8::1", ""},
+		{"ipv6 zone", "fe80::1%eth0", ""},
+		{"invalid", "203.0.113.256", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, ok := ipv4Subnet(tt.ip)
+			got := ""
+			if ok {
+				got = prefix.String()
+			}
+			if got != tt.want {
+				t.Errorf("ipv4Subnet(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,39 +1,248 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
 )
 
+const (
+	cityDBPath = "/data/geoip/GeoLite2-City.mmdb"
+	asnDBPath  = "/data/geoip/GeoLite2-ASN.mmdb"
 
+	defaultGeoIPReloadInterval = time.Minute
+)
 
-func initialize_maxmindDB() (*geoip2.Reader, *geoip2.Reader, func(), error) {
-	cityDb, err := geoip2.Open("/data/geoip/GeoLite2-City.mmdb")
+// The readers are opened with maxminddb directly (rather than geoip2.Open) so that
+// lookups also return the network the record belongs to. Records still decode
+// into the geoip2 types.
+func openMaxmindDBs() (*maxmindGeneration, error) {
+	cityDb, err := maxminddb.Open(cityDBPath)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("Issues while opening City DB")
+		return nil, fmt.Errorf("Issues while opening City DB: %w", err)
 	}
-	asnDB, err := geoip2.Open("/data/geoip/GeoLite2-ASN.mmdb")
+	asnDB, err := maxminddb.Open(asnDBPath)
 	if err != nil {
 		cityDb.Close()
-		return nil, nil, nil, fmt.Errorf("Issues while opening the ans db")
+		return nil, fmt.Errorf("Issues while opening the ans db: %w", err)
 	}
+	version := fmt.Sprintf("%d-%d", cityDb.Metadata.BuildEpoch, asnDB.Metadata.BuildEpoch)
+	return &maxmindGeneration{city: cityDb, asn: asnDB, version: version}, nil
+}
+
+// maxmindGeneration is one loaded pair of City/ASN readers. Lookups hold the
+// read lock for their duration; retiring a generation takes the write lock, so
+// readers are only closed once every in-flight lookup on them has finished.
+type maxmindGeneration struct {
+	mu     sync.RWMutex
+	closed bool
+	city   *maxminddb.Reader
+	asn    *maxminddb.Reader
+
+	version string // "<city build epoch>-<asn build epoch>"
+}
+
+func (g *maxmindGeneration) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+	g.city.Close()
+	g.asn.Close()
+}
+
+// maxmindDBs serves lookups from the current generation of readers and swaps
+// in a new one when geoipupdate replaces the files on disk.
+type maxmindDBs struct {
+	current atomic.Pointer[maxmindGeneration]
+	cityMod fileVersion
+	asnMod  fileVersion
+}
+
+func initialize_maxmindDB() (*maxmindDBs, func(), error) {
+	dbs := &maxmindDBs{}
+	_, err := dbs.reload()
 
 	cleanup := func() {
 		fmt.Println("Cleaning up and closing all the DBs")
-		cityDb.Close()
-		asnDB.Close()
+		if gen := dbs.current.Swap(nil); gen != nil {
+			gen.close()
+		}
+	}
+
+	// On error dbs is still usable: watch keeps retrying until the files load
+	return dbs, cleanup, err
+
+}
+
+// Lookup resolves ip against the current readers. Until a database pair has
+// loaded it reports errGeoUnavailable.
+func (d *maxmindDBs) Lookup(ip string) (*GeoData, error) {
+	for {
+		gen := d.current.Load()
+		if gen == nil {
+			return nil, errGeoUnavailable
+		}
+		gen.mu.RLock()
+		if gen.closed {
+			// Retired between Load and RLock; the replacement is already current
+			gen.mu.RUnlock()
+			continue
+		}
+		geo, err := lookupGeo(ip, gen.city, gen.asn)
+		gen.mu.RUnlock()
+		return geo, err
+	}
+}
+
+func (d *maxmindDBs) Name() string {
+	return "maxmind"
+}
+
+// Version is the build of the current readers, "" until a pair has loaded
+func (d *maxmindDBs) Version() string {
+	if gen := d.current.Load(); gen != nil {
+		return gen.version
+	}
+	return ""
+}
+
+func (d *maxmindDBs) Status() string {
+	if d.current.Load() == nil {
+		return geoProviderUnavailable
+	}
+	return geoProviderOK
+}
+
+// reload opens the databases again if either file changed since the last load
+func (d *maxmindDBs) reload() (bool, error) {
+	cityMod, err := statVersion(cityDBPath)
+	if err != nil {
+		return false, err
 	}
+	asnMod, err := statVersion(asnDBPath)
+	if err != nil {
+		return false, err
+	}
+	if d.current.Load() != nil && cityMod == d.cityMod && asnMod == d.asnMod {
+		return false, nil
+	}
+
+	gen, err := openMaxmindDBs()
+	if err != nil {
+		geoIPMetrics.Add("reload_errors", 1)
+		return false, err
+	}
+	d.cityMod, d.asnMod = cityMod, asnMod
+	geoIPMetrics.Set("city_build_epoch", intVar(int64(gen.city.Metadata.BuildEpoch)))
+	geoIPMetrics.Set("asn_build_epoch", intVar(int64(gen.asn.Metadata.BuildEpoch)))
+
+	previous := d.current.Swap(gen)
+	if previous == nil {
+		log.Printf("Loaded MaxMind DBs (city build %s, asn build %s)",
+			buildTime(gen.city), buildTime(gen.asn))
+		return true, nil
+	}
+	geoIPMetrics.Add("reloads", 1)
+	log.Printf("Reloaded MaxMind DBs: city build %s -> %s, asn build %s -> %s",
+		buildTime(previous.city), buildTime(gen.city), buildTime(previous.asn), buildTime(gen.asn))
+	// Waits for in-flight lookups on the old readers before closing them
+	go previous.close()
+	return true, nil
+}
 
-	return cityDb, asnDB, cleanup, nil
+func buildTime(r *maxminddb.Reader) string {
+	return time.Unix(int64(r.Metadata.BuildEpoch), 0).UTC().Format(time.RFC3339)
+}
 
+// watch polls the database files every GEOIP_RELOAD_SECONDS until ctx is cancelled.
+// geoipupdate replaces the files by rename, so the mtime/size check sees the
+// new file; the old mapping stays valid until its generation is closed.
+func (d *maxmindDBs) watch(ctx context.Context) {
+	pollReload(ctx, "GEOIP_RELOAD_SECONDS", defaultGeoIPReloadInterval, func() {
+		wasUp := d.Status() == geoProviderOK
+		if _, err := d.reload(); err != nil {
+			if wasUp {
+				log.Printf("MaxMind DB reload failed, keeping the loaded databases: %v", err)
+			} else {
+				log.Printf("MaxMind DBs still unavailable, geo fields stay empty: %v", err)
+			}
+		} else if !wasUp {
+			log.Printf("MaxMind DBs available, geo enrichment recovered")
+		}
+	})
 }
 
-func maxMindDBLookup(ip string, cityDb *geoip2.Reader, asnDB *geoip2.Reader) (*geoip2.City, *geoip2.ASN) {
+// maxMindDBLookup returns the City and ASN records for ip along with the network
+// both records are valid for. The two databases have their own network
+// boundaries, so that is the more specific (longer prefix) of the two.
+// A record that is not in a database is left nil.
+func maxMindDBLookup(ip string, cityDb *maxminddb.Reader, asnDB *maxminddb.Reader) (*geoip2.City, *geoip2.ASN, *net.IPNet, error) {
 	ans := net.ParseIP(ip)
-	city, _ := cityDb.City(ans)
-	asn, _ := asnDB.ASN(ans)
-	return city, asn
+	if ans == nil {
+		return nil, nil, nil, fmt.Errorf("invalid IP %q", ip)
+	}
+	city := &geoip2.City{}
+	asn := &geoip2.ASN{}
+	cityNet, cityFound, err := cityDb.LookupNetwork(ans, city)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("city lookup: %w", err)
+	}
+	asnNet, asnFound, err := asnDB.LookupNetwork(ans, asn)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("asn lookup: %w", err)
+	}
+	if !cityFound {
+		city = nil
+	}
+	if !asnFound {
+		asn = nil
+	}
+	return city, asn, narrowerNetwork(cityNet, asnNet), nil
+}
+
+func narrowerNetwork(a, b *net.IPNet) *net.IPNet {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	aOnes, _ := a.Mask.Size()
+	bOnes, _ := b.Mask.Size()
+	if bOnes > aOnes {
+		return b
+	}
+	return a
 }
 
+// lookupGeo builds the geo enrichment for ip from the City and ASN databases.
+// Fields of a database with no record for ip stay empty.
+func lookupGeo(ip string, cityDb *maxminddb.Reader, asnDB *maxminddb.Reader) (*GeoData, error) {
+	cityRecord, asnRecord, network, err := maxMindDBLookup(ip, cityDb, asnDB)
+	if err != nil {
+		return nil, err
+	}
+	geo := &GeoData{}
+	if cityRecord != nil {
+		geo.City = cityRecord.City.Names["en"]
+		geo.Country = cityRecord.Country.Names["en"]
+		geo.CountryCode = cityRecord.Country.IsoCode
+		geo.Latitude = cityRecord.Location.Latitude
+		geo.Longitude = cityRecord.Location.Longitude
+	}
+	if asnRecord != nil {
+		geo.ASN = fmt.Sprintf("AS%d", asnRecord.AutonomousSystemNumber)
+		geo.ISP = asnRecord.AutonomousSystemOrganization
+	}
+	if network != nil {
+		geo.Network = network.String()
+	}
+	return geo, nil
+}
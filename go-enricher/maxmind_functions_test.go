@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/oschwald/maxminddb-golang"
+
+	"fraud-enricher/internal/mmdbfixture"
+)
+
+// openFixtureDBs builds the mmdbgen fixture databases in a temporary directory
+func openFixtureDBs(t *testing.T) (*maxminddb.Reader, *maxminddb.Reader) {
+	t.Helper()
+	fx, err := mmdbfixture.Load("cmd/mmdbgen/fixture.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cityPath, asnPath, err := mmdbfixture.Write(t.TempDir(), fx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cityDB, err := maxminddb.Open(cityPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cityDB.Close() })
+	asnDB, err := maxminddb.Open(asnPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { asnDB.Close() })
+	return cityDB, asnDB
+}
+
+func TestLookupGeoFixture(t *testing.T) {
+	cityDB, asnDB := openFixtureDBs(t)
+
+	tests := []struct {
+		ip          string
+		city        string
+		countryCode string
+		asn         string
+		network     string
+		hosting     bool
+	}{
+		{"81.2.69.142", "London", "GB", "AS20712", "81.2.69.0/24", false},
+		{"89.160.20.112", "Linköping", "SE", "AS29518", "89.160.20.0/24", false},
+		// The nested /16 overrides its parent /11
+		{"52.1.2.3", "Dublin", "IE", "AS16509", "52.1.0.0/16", true},
+		{"52.20.0.1", "Ashburn", "US", "AS16509", "52.16.0.0/12", true},
+		{"2001:4860:4860::8888", "Mountain View", "US", "AS15169", "2001:4860::/32", true},
+		{"::ffff:81.2.69.142", "London", "GB", "AS20712", "81.2.69.0/24", false},
+		// Country only / ASN only
+		{"203.0.113.10", "", "AU", "", "203.0.113.0/24", false},
+		{"198.51.100.5", "", "", "AS64500", "198.51.100.0/24", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			geo, err := lookupGeo(tt.ip, cityDB, asnDB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if geo.City != tt.city || geo.CountryCode != tt.countryCode || geo.ASN != tt.asn || geo.Network != tt.network {
+				t.Errorf("lookupGeo(%s) = city %q country %q asn %q network %q; want %q %q %q %q",
+					tt.ip, geo.City, geo.CountryCode, geo.ASN, geo.Network, tt.city, tt.countryCode, tt.asn, tt.network)
+			}
+			if got := isHostingProvider(geo.ISP); got != tt.hosting {
+				t.Errorf("isHostingProvider(%q) = %v, want %v", geo.ISP, got, tt.hosting)
+			}
+		})
+	}
+}
+
+func TestMaxMindDBLookupMissing(t *testing.T) {
+	cityDB, asnDB := openFixtureDBs(t)
+
+	city, asn, _, err := maxMindDBLookup("8.8.8.8", cityDB, asnDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if city != nil || asn != nil {
+		t.Fatalf("records for an IP outside the fixture: city %+v, asn %+v", city, asn)
+	}
+	if _, _, _, err := maxMindDBLookup("not-an-ip", cityDB, asnDB); err == nil {
+		t.Fatal("no error for an invalid IP")
+	}
+}
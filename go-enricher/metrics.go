@@ -0,0 +1,94 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"net/http"
+	"os"
+)
+
+// Runtime metrics are published with expvar and served as JSON on
+// METRICS_ADDR (default :9102) at /debug/vars.
+const defaultMetricsAddr = ":9102"
+
+var (
+	// Per tier counters for the geo cache: l1_hits, l1_misses, l1_evictions,
+	// l1_expirations, l2_hits, l2_network_hits, l2_misses. l2_hits are entries
+	// seeded by the same IP, l2_network_hits by another IP in the same network
+	// (l2_network_lift is their share of L2 hits). l1_lookup_ns / l1_hits and
+	// l2_lookup_ns / (l2 hits + misses) give the average latency per tier.
+	// negative_hits counts non-routable IPs answered without any lookup.
+	// l2_errors / source_errors count failed Redis reads and provider lookups,
+	// l2_set_errors failed Redis writes, prefix_len_errors failed reads of the
+	// known prefix lengths (these failures are logged rate limited).
+	geoCacheMetrics = expvar.NewMap("geo_cache")
+
+	// Geo provider: provider, provider_status (ok/unavailable), data_version
+	// (part of every geo cache key) and for MaxMind
+	// city_build_epoch, asn_build_epoch (unix seconds of the database build),
+	// reloads, reload_errors.
+	geoIPMetrics = expvar.NewMap("geoip")
+
+	// IP reputation sources: anon_db_build_epoch, <list>_entries per loaded
+	// list (e.g. tor_exit_list_entries), reloads, reload_errors, lookup_errors.
+	reputationMetrics = expvar.NewMap("reputation")
+
+	// Transactions per network_type from the ASN categories, and
+	// keyword_fallback for ASNs the categories file doesn't list.
+	networkTypeMetrics = expvar.NewMap("network_types")
+
+	// Model scoring: model_version, challengers, scored, score_ns (score_ns /
+	// scored is the average champion latency), explain_ns (per-feature
+	// contributions), challenger_score_ns, reloads, reload_errors.
+	scoringMetrics = expvar.NewMap("scoring")
+
+	// Decisions published per outcome (ALLOW, REVIEW, BLOCK), policy_version,
+	// publish_errors, policy_reload_errors.
+	decisionMetrics = expvar.NewMap("decisions")
+
+	// Event time: watermarks (unix millis per "<topic>/<partition>"),
+	// allowed_lateness_seconds, late_events routed to the late topic,
+	// late_publish_errors, timestamp_fallbacks (transactions without a
+	// timestamp, timed by the Kafka message instead).
+	eventTimeMetrics = expvar.NewMap("event_time")
+
+	// Sequence patterns: pattern_set_version, started / completed / expired
+	// partial matches, dropped (over the per-key limit), stale (matched
+	// against a since changed pattern definition), state_errors,
+	// reload_errors.
+	cepMetrics = expvar.NewMap("cep")
+
+	// Card testing: window_seconds, min_burst, <scope>_burst_txns (small
+	// transactions inside a burst) and <scope>_escalations per scope (ip,
+	// subnet, user), state_errors.
+	cardTestingMetrics = expvar.NewMap("card_testing")
+
+	// Structuring (AML): thresholds, window_seconds, <scope>_flags per scope
+	// (user, dest), state_errors.
+	structuringMetrics = expvar.NewMap("structuring")
+)
+
+func intVar(v int64) *expvar.Int {
+	i := new(expvar.Int)
+	i.Set(v)
+	return i
+}
+
+func startMetricsServer() {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		addr = defaultMetricsAddr
+	}
+	go func() {
+		log.Printf("Serving metrics on %s/debug/vars", addr)
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+}
+
+func stringVar(v string) *expvar.String {
+	s := new(expvar.String)
+	s.Set(v)
+	return s
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Online scoring. Each model version is described by a manifest
+// (versions/<version>/manifest.yaml in the model registry, see
+// model_registry.go):
+//
+//	version: xgb-2024-06-01          # stamped on events as model_version
+//	format: xgboost                  # xgboost | lightgbm | logistic
+//	file: fraud_xgb.json             # relative to the manifest
+//	features:                        # model input name -> enriched feature
+//	  amount: transaction.amount
+//	  errorBalanceOrig: balance.error_balance_orig
+//	reference: fraud_xgb.reference.json
+//	metadata:                        # free form, e.g. trained_at, auc
+//	  trained_at: "2024-06-01"
+//
+// Without a features section the model's own input names are used as enriched
+// feature names. Booleans are fed as 0/1; a missing or non-numeric feature is
+// passed as missing (NaN) and follows the model's missing value handling.
+//
+// The reference file holds scores computed by the training code in Python
+// (see models/export_reference.py). Every case is re-scored at load time and
+// a model whose scores disagree is refused, so a format misread can never
+// reach production silently. For tree models the cases also carry the
+// library's contributions: the bias has to match, and with saabas set (path
+// attribution, the method used here) so does every input's contribution.
+type modelManifest struct {
+	Version   string            `yaml:"version"`
+	Format    string            `yaml:"format"`
+	File      string            `yaml:"file"`
+	Features  map[string]string `yaml:"features"`
+	Reference string            `yaml:"reference"`
+	Metadata  map[string]string `yaml:"metadata"`
+}
+
+// treeModel is implemented by each model format. Score gets the inputs in
+// the order of InputNames.
+//
+// Contributions explains a score on the margin (log-odds for binary models)
+// scale: a bias plus one value per input that add up to the margin, so
+// Link(bias + sum(contributions)) == Score. Tree models use path attribution
+// (Saabas): walking the decision path, each split credits its feature with
+// the change in the expected value of the tree below it.
+type treeModel interface {
+	InputNames() []string
+	Score(inputs []float64) float64
+	Link(margin float64) float64
+	Contributions(inputs []float64) (bias float64, contributions []float64)
+}
+
+type loadedModel struct {
+	version  string
+	model    treeModel
+	paths    []string // enriched feature name per model input
+	metadata map[string]string
+}
+
+type modelReference struct {
+	Tolerance float64 `json:"tolerance"`
+	Saabas    bool    `json:"saabas"`
+	Cases     []struct {
+		Features      map[string]interface{} `json:"features"`
+		Score         float64                `json:"score"`
+		Bias          *float64               `json:"bias"`
+		Contributions map[string]float64     `json:"contributions"` // model input -> contribution
+	} `json:"cases"`
+}
+
+func loadModel(manifestPath string) (*loadedModel, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var manifest modelManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", manifestPath, err)
+	}
+	if manifest.Version == "" || manifest.File == "" {
+		return nil, fmt.Errorf("%s: version and file are required", manifestPath)
+	}
+	dir := filepath.Dir(manifestPath)
+
+	modelData, err := os.ReadFile(filepath.Join(dir, manifest.File))
+	if err != nil {
+		return nil, err
+	}
+	var model treeModel
+	switch manifest.Format {
+	case "xgboost":
+		model, err = parseXGBoostModel(modelData)
+	case "lightgbm":
+		model, err = parseLightGBMModel(modelData)
+	case "logistic":
+		model, err = parseLogisticModel(modelData)
+	default:
+		return nil, fmt.Errorf("%s: unknown model format %q (xgboost, lightgbm, logistic)", manifestPath, manifest.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", manifest.File, err)
+	}
+
+	loaded := &loadedModel{version: manifest.Version, model: model, metadata: manifest.Metadata}
+	for _, name := range model.InputNames() {
+		path := name
+		if len(manifest.Features) > 0 {
+			mapped, ok := manifest.Features[name]
+			if !ok {
+				return nil, fmt.Errorf("%s: no feature mapping for model input %q", manifestPath, name)
+			}
+			path = mapped
+		}
+		loaded.paths = append(loaded.paths, path)
+	}
+
+	if manifest.Reference != "" {
+		if err := loaded.checkReference(filepath.Join(dir, manifest.Reference)); err != nil {
+			return nil, err
+		}
+	}
+	return loaded, nil
+}
+
+// inputs maps the enriched features onto the model's inputs
+func (m *loadedModel) inputs(features map[string]interface{}) []float64 {
+	inputs := make([]float64, len(m.paths))
+	for i, path := range m.paths {
+		switch v := features[path].(type) {
+		case float64:
+			inputs[i] = v
+		case bool:
+			if v {
+				inputs[i] = 1
+			}
+		default:
+			inputs[i] = math.NaN()
+		}
+	}
+	return inputs
+}
+
+func (m *loadedModel) Score(features map[string]interface{}) float64 {
+	return m.model.Score(m.inputs(features))
+}
+
+// Contributions returns the model's bias and the contribution of each
+// enriched feature it uses, on the margin scale
+func (m *loadedModel) Contributions(features map[string]interface{}) (float64, map[string]float64) {
+	bias, values := m.model.Contributions(m.inputs(features))
+	contributions := make(map[string]float64, len(values))
+	for i, value := range values {
+		contributions[m.paths[i]] += value
+	}
+	return bias, contributions
+}
+
+func (m *loadedModel) checkReference(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var ref modelReference
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(ref.Cases) == 0 {
+		return fmt.Errorf("%s: no reference cases", path)
+	}
+	tolerance := ref.Tolerance
+	if tolerance == 0 {
+		tolerance = 1e-5
+	}
+	for i, c := range ref.Cases {
+		// JSON numbers decode as float64 already, the same as enrichedFeatures
+		got := m.Score(c.Features)
+		if math.Abs(got-c.Score) > tolerance {
+			return fmt.Errorf("%s case %d: scored %.8f, reference %.8f (tolerance %g)", path, i, got, c.Score, tolerance)
+		}
+		// The contributions have to explain the score they are attached to
+		margin, contributions := m.Contributions(c.Features)
+		for _, value := range contributions {
+			margin += value
+		}
+		if explained := m.model.Link(margin); math.Abs(explained-got) > tolerance {
+			return fmt.Errorf("%s case %d: contributions add up to %.8f, score %.8f", path, i, explained, got)
+		}
+		if c.Bias != nil {
+			if err := m.checkContributions(c.Features, *c.Bias, c.Contributions, ref.Saabas, tolerance); err != nil {
+				return fmt.Errorf("%s case %d: %w", path, i, err)
+			}
+		}
+	}
+	log.Printf("Model %s matches %d reference scores from %s", m.version, len(ref.Cases), path)
+	return nil
+}
+
+// checkContributions compares the model's contributions for features with the
+// library's. The bias is the expected value of the trees under either method;
+// the per-input values only match when the library used path attribution too.
+func (m *loadedModel) checkContributions(features map[string]interface{}, bias float64, reference map[string]float64, saabas bool, tolerance float64) error {
+	gotBias, values := m.model.Contributions(m.inputs(features))
+	if math.Abs(gotBias-bias) > tolerance {
+		return fmt.Errorf("bias %.8f, reference %.8f", gotBias, bias)
+	}
+	margin := bias
+	for _, value := range reference {
+		margin += value
+	}
+	if explained := m.model.Link(margin); math.Abs(explained-m.Score(features)) > tolerance {
+		return fmt.Errorf("reference contributions add up to %.8f, score %.8f", explained, m.Score(features))
+	}
+	if !saabas {
+		return nil
+	}
+	for i, name := range m.model.InputNames() {
+		if math.Abs(values[i]-reference[name]) > tolerance {
+			return fmt.Errorf("contribution of %s %.8f, reference %.8f", name, values[i], reference[name])
+		}
+	}
+	return nil
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LightGBM models exported with Booster.dump_model() (JSON). Only numerical
+// splits and single output (binary or regression) boosters are supported.
+type lightgbmModel struct {
+	names   []string
+	trees   []*lightgbmNode
+	sigmoid float64 // 0 for raw (regression) output
+	average bool    // random forest mode averages the trees
+}
+
+type lightgbmNode struct {
+	// Internal nodes
+	SplitFeature *int          `json:"split_feature"`
+	Threshold    float64       `json:"threshold"`
+	DecisionType string        `json:"decision_type"`
+	DefaultLeft  bool          `json:"default_left"`
+	MissingType  string        `json:"missing_type"`
+	Left         *lightgbmNode `json:"left_child"`
+	Right        *lightgbmNode `json:"right_child"`
+
+	InternalCount float64 `json:"internal_count"`
+
+	// Leaves
+	LeafValue float64 `json:"leaf_value"`
+	LeafCount float64 `json:"leaf_count"`
+
+	expected float64 // count weighted mean leaf value below the node
+}
+
+type lightgbmJSON struct {
+	FeatureNames  []string `json:"feature_names"`
+	Objective     string   `json:"objective"`
+	NumClass      int      `json:"num_class"`
+	AverageOutput bool     `json:"average_output"`
+	TreeInfo      []struct {
+		TreeStructure *lightgbmNode `json:"tree_structure"`
+	} `json:"tree_info"`
+}
+
+func parseLightGBMModel(data []byte) (*lightgbmModel, error) {
+	var raw lightgbmJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.NumClass > 1 {
+		return nil, fmt.Errorf("multiclass lightgbm models are not supported")
+	}
+
+	m := &lightgbmModel{names: raw.FeatureNames, average: raw.AverageOutput}
+	// "binary sigmoid:1", "regression", ...
+	objective := strings.Fields(raw.Objective)
+	if len(objective) == 0 {
+		return nil, fmt.Errorf("model has no objective")
+	}
+	switch objective[0] {
+	case "binary", "cross_entropy", "xentropy":
+		m.sigmoid = 1
+		for _, param := range objective[1:] {
+			if value, ok := strings.CutPrefix(param, "sigmoid:"); ok {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, fmt.Errorf("objective %q: %w", raw.Objective, err)
+				}
+				m.sigmoid = parsed
+			}
+		}
+	case "regression", "regression_l2", "regression_l1", "huber":
+	default:
+		return nil, fmt.Errorf("unsupported lightgbm objective %q", raw.Objective)
+	}
+
+	for t, info := range raw.TreeInfo {
+		if info.TreeStructure == nil {
+			return nil, fmt.Errorf("tree %d has no tree_structure", t)
+		}
+		if err := m.validate(info.TreeStructure); err != nil {
+			return nil, fmt.Errorf("tree %d: %w", t, err)
+		}
+		m.trees = append(m.trees, info.TreeStructure)
+	}
+	return m, nil
+}
+
+func (m *lightgbmModel) validate(node *lightgbmNode) error {
+	if node.SplitFeature == nil {
+		node.expected = node.LeafValue
+		return nil
+	}
+	if node.DecisionType != "<=" {
+		return fmt.Errorf("decision type %q is not supported (numerical splits only)", node.DecisionType)
+	}
+	if *node.SplitFeature < 0 || *node.SplitFeature >= len(m.names) {
+		return fmt.Errorf("split on unknown feature %d", *node.SplitFeature)
+	}
+	if node.Left == nil || node.Right == nil {
+		return fmt.Errorf("split node without both children")
+	}
+	if err := m.validate(node.Left); err != nil {
+		return err
+	}
+	if err := m.validate(node.Right); err != nil {
+		return err
+	}
+	leftCount, rightCount := node.Left.count(), node.Right.count()
+	if leftCount+rightCount == 0 {
+		leftCount, rightCount = 1, 1
+	}
+	node.expected = (leftCount*node.Left.expected + rightCount*node.Right.expected) / (leftCount + rightCount)
+	return nil
+}
+
+func (n *lightgbmNode) count() float64 {
+	if n.SplitFeature == nil {
+		return n.LeafCount
+	}
+	return n.InternalCount
+}
+
+func (m *lightgbmModel) InputNames() []string {
+	return m.names
+}
+
+func (m *lightgbmModel) Score(inputs []float64) float64 {
+	raw := 0.0
+	for _, tree := range m.trees {
+		raw += tree.leaf(inputs)
+	}
+	return m.Link(m.scale() * raw)
+}
+
+// scale turns the sum of leaf values into the margin
+func (m *lightgbmModel) scale() float64 {
+	scale := 1.0
+	if m.average && len(m.trees) > 0 {
+		scale /= float64(len(m.trees))
+	}
+	if m.sigmoid > 0 {
+		scale *= m.sigmoid
+	}
+	return scale
+}
+
+func (m *lightgbmModel) Link(margin float64) float64 {
+	if m.sigmoid > 0 {
+		return sigmoid(margin)
+	}
+	return margin
+}
+
+// Contributions attributes the margin along each tree's decision path: every
+// split adds the change in expected value to the feature it split on
+func (m *lightgbmModel) Contributions(inputs []float64) (float64, []float64) {
+	scale := m.scale()
+	bias := 0.0
+	contributions := make([]float64, len(m.names))
+	for _, n := range m.trees {
+		bias += scale * n.expected
+		for n.SplitFeature != nil {
+			next := n.next(inputs)
+			contributions[*n.SplitFeature] += scale * (next.expected - n.expected)
+			n = next
+		}
+	}
+	return bias, contributions
+}
+
+func (n *lightgbmNode) leaf(inputs []float64) float64 {
+	for n.SplitFeature != nil {
+		n = n.next(inputs)
+	}
+	return n.LeafValue
+}
+
+// next follows LightGBM's numerical decision: NaN is treated as 0 unless the
+// missing type is NaN; missing values (NaN, or zero for missing type Zero)
+// follow default_left, everything else goes left when value <= threshold.
+func (n *lightgbmNode) next(inputs []float64) *lightgbmNode {
+	value := inputs[*n.SplitFeature]
+	if math.IsNaN(value) && n.MissingType != "NaN" {
+		value = 0
+	}
+	var left bool
+	switch {
+	case n.MissingType == "NaN" && math.IsNaN(value),
+		n.MissingType == "Zero" && math.Abs(value) <= 1e-35: // LightGBM's kZeroThreshold
+		left = n.DefaultLeft
+	default:
+		left = value <= n.Threshold
+	}
+	if left {
+		return n.Left
+	}
+	return n.Right
+}
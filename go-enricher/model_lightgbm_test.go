@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// A dump_model tree: f0 <= 0.5 (count 10, NaN goes left) to a -0.4 leaf
+// (count 6), else f1 <= 2 (count 4) to leaves 0.2 (count 1) and 1.0 (count
+// 3). The f1 node expects 0.8 and the root 0.08, which is the bias.
+const lightgbmContributionsDoc = `{
+	"feature_names": ["f0", "f1"],
+	"objective": "binary sigmoid:1",
+	"num_class": 1,
+	"tree_info": [{"tree_structure": {
+		"split_feature": 0, "threshold": 0.5, "decision_type": "<=", "default_left": true,
+		"missing_type": "NaN", "internal_count": 10,
+		"left_child": {"leaf_value": -0.4, "leaf_count": 6},
+		"right_child": {
+			"split_feature": 1, "threshold": 2, "decision_type": "<=", "default_left": true,
+			"missing_type": "None", "internal_count": 4,
+			"left_child": {"leaf_value": 0.2, "leaf_count": 1},
+			"right_child": {"leaf_value": 1.0, "leaf_count": 3}
+		}
+	}}]
+}`
+
+func TestLightGBMContributions(t *testing.T) {
+	m, err := parseLightGBMModel([]byte(lightgbmContributionsDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		inputs        []float64
+		contributions []float64
+		margin        float64
+	}{
+		// f0 moves 0.08 -> 0.8, f1 0.8 -> 1.0
+		{"both splits", []float64{1, 5}, []float64{0.72, 0.2}, 1.0},
+		// Missing f0 follows default_left to the -0.4 leaf; f1 is never used
+		{"missing value", []float64{math.NaN(), 5}, []float64{-0.48, 0}, -0.4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkContributions(t, m, tt.inputs, 0.08, tt.contributions, tt.margin)
+		})
+	}
+}
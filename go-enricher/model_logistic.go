@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Logistic regression exported as
+//
+//	{"intercept": -4.2, "coefficients": {"transaction.amount": 0.00001, ...}}
+//
+// e.g. from scikit-learn's intercept_[0] and coef_[0] zipped with the feature
+// names. A missing input contributes 0.
+type logisticModel struct {
+	names     []string
+	weights   []float64
+	intercept float64
+}
+
+func parseLogisticModel(data []byte) (*logisticModel, error) {
+	var raw struct {
+		Intercept    float64            `json:"intercept"`
+		Coefficients map[string]float64 `json:"coefficients"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if len(raw.Coefficients) == 0 {
+		return nil, fmt.Errorf("logistic model has no coefficients")
+	}
+	m := &logisticModel{intercept: raw.Intercept}
+	for name := range raw.Coefficients {
+		m.names = append(m.names, name)
+	}
+	sort.Strings(m.names)
+	for _, name := range m.names {
+		m.weights = append(m.weights, raw.Coefficients[name])
+	}
+	return m, nil
+}
+
+func (m *logisticModel) InputNames() []string {
+	return m.names
+}
+
+func (m *logisticModel) Score(inputs []float64) float64 {
+	bias, contributions := m.Contributions(inputs)
+	z := bias
+	for _, c := range contributions {
+		z += c
+	}
+	return sigmoid(z)
+}
+
+func (m *logisticModel) Link(margin float64) float64 {
+	return sigmoid(margin)
+}
+
+// Contributions are the weighted inputs; the intercept is the bias
+func (m *logisticModel) Contributions(inputs []float64) (float64, []float64) {
+	contributions := make([]float64, len(m.weights))
+	for i, w := range m.weights {
+		if !math.IsNaN(inputs[i]) {
+			contributions[i] = w * inputs[i]
+		}
+	}
+	return m.intercept, contributions
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Filesystem model registry (MODEL_REGISTRY, default "models"):
+//
+//	models/
+//	  promoted.yaml                  champion + challengers in service
+//	  versions/<version>/manifest.yaml
+//	  versions/<version>/...         model and reference files
+//
+// promoted.yaml:
+//
+//	champion: xgb-2024-06-01
+//	challengers: [xgb-2024-07-01, lgb-2024-07-01]
+//
+// Versions are immutable once published; rolling out a model means editing
+// promoted.yaml (cmd/modelctl does it atomically). The pointer is polled every
+// MODEL_RELOAD_SECONDS and a changed lineup is loaded and swapped in whole.
+// If the new champion fails to load (including its reference check) the
+// current lineup stays; a challenger that fails is left out and retried
+// every poll until it loads or is dropped from the pointer.
+//
+// Only the champion's score is acted on (fraud_score, rules, decisions).
+// Challenger scores are recorded on the event as challenger_scores so they
+// can be compared offline.
+const (
+	defaultModelRegistry      = "models"
+	defaultModelReloadSeconds = 30
+	modelPromotedFile         = "promoted.yaml"
+)
+
+type modelPointer struct {
+	Champion    string   `yaml:"champion"`
+	Challengers []string `yaml:"challengers"`
+}
+
+type modelLineup struct {
+	champion    *loadedModel
+	challengers []*loadedModel
+	missing     []string // challengers named by the pointer that failed to load
+	pointerHash string
+}
+
+// modelScorer holds the lineup in service. With no champion loaded, events
+// are published unscored (empty model_version).
+type modelScorer struct {
+	current  atomic.Pointer[modelLineup]
+	registry string
+}
+
+func newModelScorer() *modelScorer {
+	s := &modelScorer{registry: os.Getenv("MODEL_REGISTRY")}
+	if s.registry == "" {
+		s.registry = defaultModelRegistry
+	}
+	if _, err := s.reload(); err != nil {
+		log.Printf("WARNING: no fraud model loaded from registry %s, events will be unscored: %v", s.registry, err)
+	}
+	return s
+}
+
+func (s *modelScorer) manifestPath(version string) string {
+	return filepath.Join(s.registry, "versions", version, "manifest.yaml")
+}
+
+// reload loads the lineup named by promoted.yaml if the pointer changed or a
+// challenger is still missing
+func (s *modelScorer) reload() (bool, error) {
+	data, err := os.ReadFile(filepath.Join(s.registry, modelPromotedFile))
+	if err != nil {
+		return false, err
+	}
+	hash := contentHash(data)
+	current := s.current.Load()
+	if current != nil && current.pointerHash == hash && len(current.missing) == 0 {
+		return false, nil
+	}
+	var pointer modelPointer
+	if err := yaml.Unmarshal(data, &pointer); err != nil {
+		return false, fmt.Errorf("parsing %s: %w", modelPromotedFile, err)
+	}
+	if pointer.Champion == "" {
+		return false, fmt.Errorf("%s names no champion", modelPromotedFile)
+	}
+
+	// Models already in service are reused rather than re-read
+	loaded := make(map[string]*loadedModel)
+	if current != nil {
+		for _, m := range append([]*loadedModel{current.champion}, current.challengers...) {
+			loaded[m.version] = m
+		}
+	}
+	load := func(version string) (*loadedModel, error) {
+		if m, ok := loaded[version]; ok {
+			return m, nil
+		}
+		m, err := loadModel(s.manifestPath(version))
+		if err != nil {
+			return nil, err
+		}
+		if m.version != version {
+			return nil, fmt.Errorf("manifest version %q does not match registry directory %q", m.version, version)
+		}
+		return m, nil
+	}
+
+	champion, err := load(pointer.Champion)
+	if err != nil {
+		scoringMetrics.Add("reload_errors", 1)
+		return false, fmt.Errorf("champion %s: %w", pointer.Champion, err)
+	}
+	lineup := &modelLineup{champion: champion, pointerHash: hash}
+	for _, version := range pointer.Challengers {
+		if version == pointer.Champion {
+			continue
+		}
+		challenger, err := load(version)
+		if err != nil {
+			scoringMetrics.Add("reload_errors", 1)
+			log.Printf("Challenger model %s left out: %v", version, err)
+			lineup.missing = append(lineup.missing, version)
+			continue
+		}
+		lineup.challengers = append(lineup.challengers, challenger)
+	}
+	// Models in service are reused, so with the same pointer the missing
+	// challengers can only shrink; none loading leaves the lineup as it is
+	if current != nil && current.pointerHash == hash && len(lineup.missing) == len(current.missing) {
+		return false, nil
+	}
+
+	s.current.Store(lineup)
+	scoringMetrics.Set("model_version", stringVar(champion.version))
+	scoringMetrics.Set("challengers", stringVar(fmt.Sprint(lineup.versions()[1:])))
+	if current == nil {
+		log.Printf("Loaded fraud model %s (%d inputs, metadata %v), challengers %v",
+			champion.version, len(champion.paths), champion.metadata, lineup.versions()[1:])
+	} else {
+		scoringMetrics.Add("reloads", 1)
+		log.Printf("Model lineup changed: champion %s -> %s, challengers %v",
+			current.champion.version, champion.version, lineup.versions()[1:])
+	}
+	return true, nil
+}
+
+func (l *modelLineup) versions() []string {
+	versions := []string{l.champion.version}
+	for _, c := range l.challengers {
+		versions = append(versions, c.version)
+	}
+	return versions
+}
+
+// modelResult is the champion's score with its per-feature contributions,
+// and the shadow score of every challenger
+type modelResult struct {
+	Score         float64
+	Version       string
+	BaseValue     float64            // contribution of no feature (margin scale)
+	Contributions map[string]float64 // enriched feature -> contribution (margin scale)
+	Challengers   map[string]float64 // challenger version -> score
+}
+
+// Score runs the lineup in service, or returns ok=false when no model is loaded
+func (s *modelScorer) Score(features map[string]interface{}) (result modelResult, ok bool) {
+	lineup := s.current.Load()
+	if lineup == nil {
+		return result, false
+	}
+	start := time.Now()
+	result.Score = lineup.champion.Score(features)
+	result.Version = lineup.champion.version
+	scoringMetrics.Add("scored", 1)
+	scoringMetrics.Add("score_ns", time.Since(start).Nanoseconds())
+
+	explainStart := time.Now()
+	result.BaseValue, result.Contributions = lineup.champion.Contributions(features)
+	scoringMetrics.Add("explain_ns", time.Since(explainStart).Nanoseconds())
+
+	if len(lineup.challengers) > 0 {
+		challengerStart := time.Now()
+		result.Challengers = make(map[string]float64, len(lineup.challengers))
+		for _, c := range lineup.challengers {
+			result.Challengers[c.version] = c.Score(features)
+		}
+		scoringMetrics.Add("challenger_score_ns", time.Since(challengerStart).Nanoseconds())
+	}
+	return result, true
+}
+
+// watch polls the promoted pointer until ctx is cancelled
+func (s *modelScorer) watch(ctx context.Context) {
+	pollReload(ctx, "MODEL_RELOAD_SECONDS", time.Duration(defaultModelReloadSeconds)*time.Second, func() {
+		if _, err := s.reload(); err != nil {
+			log.Printf("Model reload failed, keeping the current lineup: %v", err)
+		}
+	})
+}
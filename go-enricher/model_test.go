@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Every model in the registry and every fixture under testdata/models has to
+// load, which includes matching its reference scores and explaining them with
+// its contributions (checkReference).
+func TestModelReferences(t *testing.T) {
+	registry, err := filepath.Glob("models/versions/*/manifest.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures, err := filepath.Glob("testdata/models/*/manifest.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range append(registry, fixtures...) {
+		t.Run(path, func(t *testing.T) {
+			manifest := readManifest(t, path)
+			if manifest.Reference == "" {
+				t.Fatalf("%s has no reference scores", path)
+			}
+			if _, err := loadModel(path); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// The tree parsers and their contributions are only trustworthy against
+// exports written by the real libraries (models/train_fixtures.py), so each
+// format needs a fixture whose reference carries contributions comparable one
+// for one (saabas); a missing one fails rather than letting the format go
+// unchecked. TestModelReferences does the comparing, through loadModel.
+func TestModelFormatsHaveLibraryFixtures(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/models/*/manifest.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	formats := make(map[string]bool)
+	for _, path := range fixtures {
+		manifest := readManifest(t, path)
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(path), manifest.Reference))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ref modelReference
+		if err := json.Unmarshal(data, &ref); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		withContributions := len(ref.Cases) > 0
+		for _, c := range ref.Cases {
+			withContributions = withContributions && c.Bias != nil && len(c.Contributions) > 0
+		}
+		if !withContributions {
+			t.Errorf("%s: reference has no library contributions; regenerate it with models/export_reference.py", path)
+		}
+		formats[manifest.Format] = formats[manifest.Format] || ref.Saabas && withContributions
+	}
+	var missing []string
+	for _, format := range []string{"xgboost", "lightgbm"} {
+		if !formats[format] {
+			missing = append(missing, format)
+		}
+	}
+	if len(missing) > 0 {
+		t.Fatalf("no %v fixture under testdata/models; generate with models/train_fixtures.py (needs xgboost and lightgbm) and commit it", missing)
+	}
+}
+
+// A challenger that fails to load is retried on the next poll, without
+// promoted.yaml having to change
+func TestModelScorerRetriesMissingChallenger(t *testing.T) {
+	registry := t.TempDir()
+	publishModelCopy(t, registry, "baseline-lr-v1")
+	pointer := "champion: baseline-lr-v1\nchallengers: [baseline-lr-v2]\n"
+	if err := os.WriteFile(filepath.Join(registry, modelPromotedFile), []byte(pointer), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &modelScorer{registry: registry}
+	for i, want := range []struct {
+		changed  bool
+		versions string
+	}{
+		{true, "[baseline-lr-v1]"},  // the challenger isn't published yet
+		{false, "[baseline-lr-v1]"}, // still missing: retried, lineup kept
+		{true, "[baseline-lr-v1 baseline-lr-v2]"},
+		{false, "[baseline-lr-v1 baseline-lr-v2]"},
+	} {
+		if i == 2 {
+			publishModelCopy(t, registry, "baseline-lr-v2")
+		}
+		changed, err := s.reload()
+		if err != nil {
+			t.Fatalf("reload %d: %v", i, err)
+		}
+		if versions := fmt.Sprint(s.current.Load().versions()); changed != want.changed || versions != want.versions {
+			t.Fatalf("reload %d = %v, lineup %s; want %v, %s", i, changed, versions, want.changed, want.versions)
+		}
+	}
+}
+
+// publishModelCopy publishes the baseline model as version in registry
+func publishModelCopy(t *testing.T, registry string, version string) {
+	t.Helper()
+	src := "models/versions/baseline-lr-v1"
+	dst := filepath.Join(registry, "versions", version)
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(src, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Name() == "manifest.yaml" {
+			data = []byte(strings.Replace(string(data), "version: baseline-lr-v1", "version: "+version, 1))
+		}
+		if err := os.WriteFile(filepath.Join(dst, entry.Name()), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readManifest(t *testing.T, path string) modelManifest {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest modelManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return manifest
+}
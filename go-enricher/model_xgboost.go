@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// XGBoost models saved with Booster.save_model("model.json"). Only numeric
+// splits and single output (binary or regression) boosters are supported.
+type xgboostModel struct {
+	names    []string
+	trees    []xgboostTree
+	baseMarg float64
+	logistic bool // apply the sigmoid to the margin
+}
+
+type xgboostTree struct {
+	left, right []int
+	splitIndex  []int
+	splitCond   []float32 // threshold, or the leaf value on leaves
+	defaultLeft []bool
+	expected    []float64 // cover weighted mean leaf value below each node
+}
+
+type xgboostJSON struct {
+	Learner struct {
+		FeatureNames    []string `json:"feature_names"`
+		GradientBooster struct {
+			Name  string `json:"name"`
+			Model struct {
+				Trees []struct {
+					LeftChildren    []int           `json:"left_children"`
+					RightChildren   []int           `json:"right_children"`
+					SplitIndices    []int           `json:"split_indices"`
+					SplitConditions []float64       `json:"split_conditions"`
+					DefaultLeft     json.RawMessage `json:"default_left"`
+					SplitType       []int           `json:"split_type"`
+					SumHessian      []float64       `json:"sum_hessian"`
+				} `json:"trees"`
+			} `json:"model"`
+		} `json:"gradient_booster"`
+		LearnerModelParam struct {
+			BaseScore  string `json:"base_score"`
+			NumFeature string `json:"num_feature"`
+			NumClass   string `json:"num_class"`
+		} `json:"learner_model_param"`
+		Objective struct {
+			Name string `json:"name"`
+		} `json:"objective"`
+	} `json:"learner"`
+}
+
+func parseXGBoostModel(data []byte) (*xgboostModel, error) {
+	var raw xgboostJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	learner := raw.Learner
+	if learner.GradientBooster.Name != "gbtree" {
+		return nil, fmt.Errorf("unsupported xgboost booster %q (gbtree only)", learner.GradientBooster.Name)
+	}
+	if n, _ := strconv.Atoi(learner.LearnerModelParam.NumClass); n > 1 {
+		return nil, fmt.Errorf("multiclass xgboost models are not supported")
+	}
+
+	m := &xgboostModel{names: learner.FeatureNames}
+	if len(m.names) == 0 {
+		numFeature, err := strconv.Atoi(learner.LearnerModelParam.NumFeature)
+		if err != nil {
+			return nil, fmt.Errorf("model has neither feature_names nor num_feature")
+		}
+		for i := 0; i < numFeature; i++ {
+			m.names = append(m.names, fmt.Sprintf("f%d", i))
+		}
+	}
+
+	// base_score is "5E-1", or "[5E-1]" from XGBoost 3
+	baseScore, err := strconv.ParseFloat(strings.Trim(learner.LearnerModelParam.BaseScore, "[]"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("base_score %q: %w", learner.LearnerModelParam.BaseScore, err)
+	}
+	switch learner.Objective.Name {
+	case "binary:logistic", "reg:logistic":
+		// base_score is a probability for these objectives
+		m.logistic = true
+		m.baseMarg = math.Log(baseScore / (1 - baseScore))
+	case "binary:logitraw", "reg:squarederror", "reg:linear":
+		m.baseMarg = baseScore
+	default:
+		return nil, fmt.Errorf("unsupported xgboost objective %q", learner.Objective.Name)
+	}
+
+	for t, tree := range learner.GradientBooster.Model.Trees {
+		for _, splitType := range tree.SplitType {
+			if splitType != 0 {
+				return nil, fmt.Errorf("tree %d: categorical splits are not supported", t)
+			}
+		}
+		defaultLeft, err := parseFlexBools(tree.DefaultLeft)
+		if err != nil {
+			return nil, fmt.Errorf("tree %d default_left: %w", t, err)
+		}
+		n := len(tree.LeftChildren)
+		if n == 0 {
+			return nil, fmt.Errorf("tree %d has no nodes", t)
+		}
+		if len(tree.RightChildren) != n || len(tree.SplitIndices) != n || len(tree.SplitConditions) != n || len(defaultLeft) != n || len(tree.SumHessian) != n {
+			return nil, fmt.Errorf("tree %d: node arrays differ in length", t)
+		}
+		if err := checkXGBoostTree(tree.LeftChildren, tree.RightChildren); err != nil {
+			return nil, fmt.Errorf("tree %d: %w", t, err)
+		}
+		parsed := xgboostTree{
+			left:        tree.LeftChildren,
+			right:       tree.RightChildren,
+			splitIndex:  tree.SplitIndices,
+			splitCond:   make([]float32, n),
+			defaultLeft: defaultLeft,
+		}
+		for i, cond := range tree.SplitConditions {
+			parsed.splitCond[i] = float32(cond)
+			if tree.LeftChildren[i] != -1 && (tree.SplitIndices[i] < 0 || tree.SplitIndices[i] >= len(m.names)) {
+				return nil, fmt.Errorf("tree %d node %d: split on unknown feature %d", t, i, tree.SplitIndices[i])
+			}
+		}
+		parsed.expected = make([]float64, n)
+		parsed.fillExpected(0, tree.SumHessian)
+		m.trees = append(m.trees, parsed)
+	}
+	return m, nil
+}
+
+// checkXGBoostTree makes sure the child links form a tree rooted at node 0:
+// every child is -1 (leaf) on both sides or a node index, and no node is
+// reached twice. Walking a malformed export would otherwise panic or loop.
+func checkXGBoostTree(left, right []int) error {
+	seen := make([]bool, len(left))
+	stack := []int{0}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[node] {
+			return fmt.Errorf("node %d is reached twice", node)
+		}
+		seen[node] = true
+		if left[node] == -1 && right[node] == -1 {
+			continue
+		}
+		for _, child := range []int{left[node], right[node]} {
+			if child < 0 || child >= len(left) {
+				return fmt.Errorf("node %d: child %d out of range", node, child)
+			}
+			stack = append(stack, child)
+		}
+	}
+	return nil
+}
+
+// fillExpected computes the expected value of every node from its children,
+// weighted by cover (sum_hessian), and returns the node's cover
+func (t *xgboostTree) fillExpected(node int, cover []float64) float64 {
+	weight := cover[node]
+	if t.left[node] == -1 {
+		t.expected[node] = float64(t.splitCond[node])
+		return weight
+	}
+	leftCover := t.fillExpected(t.left[node], cover)
+	rightCover := t.fillExpected(t.right[node], cover)
+	if leftCover+rightCover > 0 {
+		t.expected[node] = (leftCover*t.expected[t.left[node]] + rightCover*t.expected[t.right[node]]) / (leftCover + rightCover)
+	}
+	return weight
+}
+
+// parseFlexBools accepts default_left as 0/1 numbers (current format) or booleans
+func parseFlexBools(raw json.RawMessage) ([]bool, error) {
+	var values []interface{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	out := make([]bool, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case bool:
+			out[i] = v
+		case float64:
+			out[i] = v != 0
+		default:
+			return nil, fmt.Errorf("unexpected value %v", v)
+		}
+	}
+	return out, nil
+}
+
+func (m *xgboostModel) InputNames() []string {
+	return m.names
+}
+
+func (m *xgboostModel) Score(inputs []float64) float64 {
+	margin := m.baseMarg
+	for i := range m.trees {
+		margin += float64(m.trees[i].splitCond[m.trees[i].leaf(inputs)])
+	}
+	return m.Link(margin)
+}
+
+func (m *xgboostModel) Link(margin float64) float64 {
+	if m.logistic {
+		return sigmoid(margin)
+	}
+	return margin
+}
+
+// Contributions attributes the margin along each tree's decision path: every
+// split adds the change in expected value to the feature it split on
+func (m *xgboostModel) Contributions(inputs []float64) (float64, []float64) {
+	bias := m.baseMarg
+	contributions := make([]float64, len(m.names))
+	for i := range m.trees {
+		t := &m.trees[i]
+		bias += t.expected[0]
+		node := 0
+		for t.left[node] != -1 {
+			next := t.next(node, inputs)
+			contributions[t.splitIndex[node]] += t.expected[next] - t.expected[node]
+			node = next
+		}
+	}
+	return bias, contributions
+}
+
+// leaf walks the tree from the root and returns the leaf node reached
+func (t *xgboostTree) leaf(inputs []float64) int {
+	node := 0
+	for t.left[node] != -1 {
+		node = t.next(node, inputs)
+	}
+	return node
+}
+
+// next takes one step the way XGBoost does: go left when value < threshold
+// (compared as float32), missing values follow default_left.
+func (t *xgboostTree) next(node int, inputs []float64) int {
+	value := inputs[t.splitIndex[node]]
+	switch {
+	case math.IsNaN(value):
+		if t.defaultLeft[node] {
+			return t.left[node]
+		}
+		return t.right[node]
+	case float32(value) < t.splitCond[node]:
+		return t.left[node]
+	default:
+		return t.right[node]
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// xgboostDoc wraps tree objects in a binary:logistic model over f0, f1
+func xgboostDoc(trees ...string) []byte {
+	return []byte(fmt.Sprintf(`{"learner": {
+		"feature_names": ["f0", "f1"],
+		"gradient_booster": {"name": "gbtree", "model": {"trees": [%s]}},
+		"learner_model_param": {"base_score": "5E-1", "num_feature": "2", "num_class": "0"},
+		"objective": {"name": "binary:logistic"}
+	}}`, strings.Join(trees, ",")))
+}
+
+// xgboostTreeDoc is one tree in save_model's column layout
+func xgboostTreeDoc(left, right, split []int, cond, hessian []float64) string {
+	defaults := make([]int, len(left))
+	return fmt.Sprintf(`{"left_children": %s, "right_children": %s, "split_indices": %s,
+		"split_conditions": %s, "default_left": %s, "split_type": %s, "sum_hessian": %s}`,
+		jsonList(left), jsonList(right), jsonList(split), jsonList(cond), jsonList(defaults), jsonList(defaults), jsonList(hessian))
+}
+
+func jsonList[T any](values []T) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+func TestParseXGBoostModelRejectsMalformedTrees(t *testing.T) {
+	tests := []struct {
+		name string
+		tree string
+		want string
+	}{
+		{"no nodes", xgboostTreeDoc(nil, nil, nil, nil, nil), "has no nodes"},
+		{"sum_hessian missing",
+			xgboostTreeDoc([]int{1, -1, -1}, []int{2, -1, -1}, []int{0, 0, 0}, []float64{0.5, -0.4, 0.6}, nil),
+			"differ in length"},
+		{"child out of range",
+			xgboostTreeDoc([]int{1, -1, -1}, []int{3, -1, -1}, []int{0, 0, 0}, []float64{0.5, -0.4, 0.6}, []float64{10, 6, 4}),
+			"out of range"},
+		{"negative child",
+			xgboostTreeDoc([]int{1, -1, -1}, []int{-2, -1, -1}, []int{0, 0, 0}, []float64{0.5, -0.4, 0.6}, []float64{10, 6, 4}),
+			"out of range"},
+		{"one sided leaf",
+			xgboostTreeDoc([]int{1, -1, -1}, []int{2, -1, 0}, []int{0, 0, 0}, []float64{0.5, -0.4, 0.6}, []float64{10, 6, 4}),
+			"out of range"},
+		{"cycle",
+			xgboostTreeDoc([]int{1, 0, -1}, []int{2, 2, -1}, []int{0, 1, 0}, []float64{0.5, 0.5, 0.6}, []float64{10, 6, 4}),
+			"reached twice"},
+		{"unknown feature",
+			xgboostTreeDoc([]int{1, -1, -1}, []int{2, -1, -1}, []int{7, 0, 0}, []float64{0.5, -0.4, 0.6}, []float64{10, 6, 4}),
+			"unknown feature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseXGBoostModel(xgboostDoc(tt.tree))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("parseXGBoostModel error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// Path attribution on a hand-built model: tree 0 splits f0 < 0.5 (cover 10)
+// into node 1, f1 < 2 (cover 6; leaves -0.4 cover 2 and 0.2 cover 4), and a
+// 0.6 leaf (cover 4), so node 1 expects 0 and the root 0.24. Tree 1 is a stump
+// on f1 < 1 with leaves 0.1 and -0.3 of equal cover, expecting -0.1. The bias
+// is base_score's margin (0) plus both root expectations.
+func TestXGBoostContributions(t *testing.T) {
+	m, err := parseXGBoostModel(xgboostDoc(
+		xgboostTreeDoc([]int{1, 3, -1, -1, -1}, []int{2, 4, -1, -1, -1}, []int{0, 1, 0, 0, 0},
+			[]float64{0.5, 2, 0.6, -0.4, 0.2}, []float64{10, 6, 4, 2, 4}),
+		xgboostTreeDoc([]int{1, -1, -1}, []int{2, -1, -1}, []int{1, 0, 0}, []float64{1, 0.1, -0.3}, []float64{10, 5, 5}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		inputs        []float64
+		contributions []float64
+		margin        float64
+	}{
+		// f0 takes the root to node 1 (0.24 -> 0); f1 reaches 0.2 in tree 0
+		// (+0.2) and -0.3 in tree 1 (-0.2)
+		{"both splits", []float64{0.2, 3}, []float64{-0.24, 0}, -0.1},
+		// Missing f0 goes right (default_left 0) to the 0.6 leaf
+		{"missing value", []float64{math.NaN(), 0}, []float64{0.36, 0.2}, 0.7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkContributions(t, m, tt.inputs, 0.14, tt.contributions, tt.margin)
+		})
+	}
+}
+
+// loadModel refuses a model whose contributions disagree with the reference
+func TestLoadModelChecksReferenceContributions(t *testing.T) {
+	dir := t.TempDir()
+	model := xgboostDoc(xgboostTreeDoc([]int{1, -1, -1}, []int{2, -1, -1}, []int{0, 0, 0}, []float64{0.5, -0.4, 0.6}, []float64{10, 6, 4}))
+	manifest := "version: contrib-check\nformat: xgboost\nfile: model.json\nreference: reference.json\n"
+	// f0 = 1 reaches the 0.6 leaf: bias 0 + 0.6*0.4 - 0.4*0.6 = 0, f0 +0.6
+	for _, tt := range []struct {
+		name      string
+		reference string
+		wantErr   string
+	}{
+		{"matching", `{"saabas": true, "cases": [{"features": {"f0": 1, "f1": 0}, "score": %v, "bias": 0, "contributions": {"f0": 0.6, "f1": 0}}]}`, ""},
+		{"wrong contribution", `{"saabas": true, "cases": [{"features": {"f0": 1, "f1": 0}, "score": %v, "bias": 0, "contributions": {"f0": 0.5, "f1": 0.1}}]}`, "contribution of f0"},
+		{"wrong bias", `{"saabas": true, "cases": [{"features": {"f0": 1, "f1": 0}, "score": %v, "bias": 0.1, "contributions": {"f0": 0.5, "f1": 0}}]}`, "bias"},
+		// TreeSHAP values only have to add up
+		{"shap sum", `{"saabas": false, "cases": [{"features": {"f0": 1, "f1": 0}, "score": %v, "bias": 0, "contributions": {"f0": 0.5, "f1": 0.1}}]}`, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{
+				"manifest.yaml":  manifest,
+				"model.json":     string(model),
+				"reference.json": fmt.Sprintf(tt.reference, sigmoid(float64(float32(0.6)))),
+			}
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := loadModel(filepath.Join(dir, "manifest.yaml"))
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("loadModel error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// checkContributions compares a model's path attribution with hand computed
+// values and checks that bias plus contributions is the margin behind Score
+func checkContributions(t *testing.T, m treeModel, inputs []float64, bias float64, contributions []float64, margin float64) {
+	t.Helper()
+	const tolerance = 1e-6 // leaf values are float32 in XGBoost
+	gotBias, got := m.Contributions(inputs)
+	if math.Abs(gotBias-bias) > tolerance {
+		t.Errorf("bias = %.8f, want %.8f", gotBias, bias)
+	}
+	sum := gotBias
+	for i, want := range contributions {
+		if math.Abs(got[i]-want) > tolerance {
+			t.Errorf("contribution %d = %.8f, want %.8f", i, got[i], want)
+		}
+		sum += got[i]
+	}
+	if math.Abs(sum-margin) > tolerance {
+		t.Errorf("bias + contributions = %.8f, want the margin %.8f", sum, margin)
+	}
+	if score := m.Score(inputs); math.Abs(score-m.Link(margin)) > tolerance {
+		t.Errorf("Score = %.8f, want Link(margin) = %.8f", score, m.Link(margin))
+	}
+}
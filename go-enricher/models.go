@@ -1,6 +1,10 @@
 package main
 
-import "time"
+import (
+	"time"
+
+	pb "fraud-enricher/pb"
+)
 
 type GeoData struct {
 	City        string  `json:"city"`
@@ -10,7 +14,7 @@ type GeoData struct {
 	Longitude   float64 `json:"lon"`
 	ASN         string  `json:"asn"`
 	ISP         string  `json:"isp"`
-	IsHosting   bool    `json:"is_hosting"`
+	Network     string  `json:"network"` // CIDR the geo record applies to
 }
 
 type FraudSignals struct {
@@ -23,32 +27,46 @@ type FraudSignals struct {
 	MaxAmount      float64   `json:"max_amount"`
 }
 
-type EnrichedTransaction struct {
-	TransactionID            string  `json:"transaction_id"`
-	UserID                   string  `json:"user_id"`
-	Amount                   float64 `json:"amount"`
-	Timestamp                int64   `json:"timestamp"`
-	IsFraud                  bool    `json:"is_fraud"`
-	Type                     string  `json:"type"`
-	OldBalanceOrig           float64 `json:"old_balance_orig"`
-	NewBalanceOrig           float64 `json:"new_balance_orig"`
-	OldBalanceDest           float64 `json:"old_balance_dest"`
-	NewBalanceDest           float64 `json:"new_balance_dest"`
-	IsUnauthorizedOverdraft  float64 `json:"is_unauthorized_overdraft"`
-	
-	IPAddress   string  `json:"ip_address"`
-	City        string  `json:"city"`
-	Country     string  `json:"country"`
-	CountryCode string  `json:"country_code"`
-	Latitude    float64 `json:"latitude"`
-	Longitude   float64 `json:"longitude"`
-	ASN         string  `json:"asn"`
-	ISP         string  `json:"isp"`
-	IsHosting   bool    `json:"is_hosting"`
-	
-	TxnCount2h     int     `json:"txn_count_2h"`
-	TotalAmount2h  float64 `json:"total_amount_2h"`
-	AmountVelocity float64 `json:"amount_velocity"`
-	AvgAmount2h    float64 `json:"avg_amount_2h"`
-	MaxAmount2h    float64 `json:"max_amount_2h"`
-}
\ No newline at end of file
+// AggregateSignals are windowed totals for a group of IPs (see aggregates.go)
+type AggregateSignals struct {
+	Key           string  `json:"key"`
+	TxnCount      int     `json:"txn_count"`
+	DistinctUsers int     `json:"distinct_users"`
+	TotalAmount   float64 `json:"total_amount"`
+	WindowSeconds int64   `json:"window_seconds"`
+}
+
+func (g *GeoData) toProto() *pb.GeoData {
+	return &pb.GeoData{
+		City:        g.City,
+		Country:     g.Country,
+		CountryCode: g.CountryCode,
+		Latitude:    g.Latitude,
+		Longitude:   g.Longitude,
+		Asn:         g.ASN,
+		Isp:         g.ISP,
+		Network:     g.Network,
+	}
+}
+
+func (f *FraudSignals) toProto() *pb.FraudSignals {
+	return &pb.FraudSignals{
+		FirstSeen:      f.FirstSeen.UnixMilli(),
+		LastSeen:       f.LastSeen.UnixMilli(),
+		TxnCount:       int64(f.TxnCount),
+		TotalAmount:    f.TotalAmount,
+		AmountVelocity: f.AmountVelocity,
+		AvgAmount:      f.AvgAmount,
+		MaxAmount:      f.MaxAmount,
+	}
+}
+
+func (a *AggregateSignals) toProto() *pb.AggregateSignals {
+	return &pb.AggregateSignals{
+		Key:           a.Key,
+		TxnCount:      int64(a.TxnCount),
+		DistinctUsers: int64(a.DistinctUsers),
+		TotalAmount:   a.TotalAmount,
+		WindowSeconds: a.WindowSeconds,
+	}
+}
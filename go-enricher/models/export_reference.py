@@ -0,0 +1,132 @@
+"""Write the reference scores the enricher checks a model against at load time.
+
+XGBoost and LightGBM scores are produced by the library itself, so the Go
+parsers are verified against the real thing rather than against themselves.
+Logistic models have no library behind them: their scores are computed here
+from the coefficients, which only cross-checks the Go arithmetic.
+
+    python export_reference.py versions/<version>/manifest.yaml cases.json > versions/<version>/reference.json
+
+cases.json is a list of feature dicts keyed by enriched feature name (the
+right-hand side of the manifest's `features` section), e.g.
+[{"transaction.amount": 250.5, "balance.orig_drained": true}, ...].
+Features left out of a case are passed as missing.
+
+For the tree formats every case also carries the library's per-feature
+contributions and bias on the margin scale (XGBoost pred_contribs with
+approx_contribs, i.e. the path attribution the Go scorer uses; LightGBM
+pred_contrib, which is TreeSHAP). "saabas" says whether they are path
+attributions and so must match the Go values one for one: always for XGBoost,
+and for LightGBM only when every tree is a single split, where TreeSHAP and
+path attribution agree. Otherwise only the bias and the sum are comparable.
+
+LightGBM models are exported with dump_model(), which LightGBM can't load
+back, so pass the native model file with --lightgbm-model model.txt.
+Requires PyYAML, plus xgboost / lightgbm for those formats.
+"""
+import argparse
+import json
+import math
+import os
+import sys
+
+import yaml
+
+
+def feature_value(case, path):
+    value = case.get(path)
+    if isinstance(value, bool):
+        return 1.0 if value else 0.0
+    if isinstance(value, (int, float)):
+        return float(value)
+    return float("nan")
+
+
+def main():
+    parser = argparse.ArgumentParser()
+    parser.add_argument("manifest")
+    parser.add_argument("cases")
+    parser.add_argument("--lightgbm-model")
+    parser.add_argument("--tolerance", type=float, default=1e-5)
+    args = parser.parse_args()
+
+    with open(args.manifest) as f:
+        manifest = yaml.safe_load(f)
+    with open(args.cases) as f:
+        cases = json.load(f)
+    model_path = os.path.join(os.path.dirname(args.manifest), manifest["file"])
+    mapping = manifest.get("features") or {}
+
+    fmt = manifest["format"]
+    contribs = None
+    saabas = False
+    if fmt == "logistic":
+        with open(model_path) as f:
+            model = json.load(f)
+        names = sorted(model["coefficients"])
+
+        def score(rows):
+            out = []
+            for row in rows:
+                z = model["intercept"] + sum(
+                    model["coefficients"][n] * v for n, v in zip(names, row) if not math.isnan(v)
+                )
+                out.append(1.0 / (1.0 + math.exp(-z)))
+            return out
+    elif fmt == "xgboost":
+        import xgboost
+
+        booster = xgboost.Booster(model_file=model_path)
+        names = booster.feature_names or ["f%d" % i for i in range(booster.num_features())]
+
+        def matrix(rows):
+            return xgboost.DMatrix(rows, feature_names=booster.feature_names, missing=float("nan"))
+
+        def score(rows):
+            return booster.predict(matrix(rows)).tolist()
+
+        def contribs(rows):
+            return booster.predict(matrix(rows), pred_contribs=True, approx_contribs=True).tolist()
+
+        saabas = True
+    elif fmt == "lightgbm":
+        import lightgbm
+
+        if not args.lightgbm_model:
+            sys.exit("--lightgbm-model is required for lightgbm manifests")
+        booster = lightgbm.Booster(model_file=args.lightgbm_model)
+        names = booster.feature_name()
+
+        def score(rows):
+            return booster.predict(rows).tolist()
+
+        def contribs(rows):
+            return booster.predict(rows, pred_contrib=True).tolist()
+
+        def depth(node):
+            if "split_feature" not in node:
+                return 0
+            return 1 + max(depth(node["left_child"]), depth(node["right_child"]))
+
+        trees = booster.dump_model()["tree_info"]
+        saabas = all(depth(tree["tree_structure"]) <= 1 for tree in trees)
+    else:
+        sys.exit("unknown format %r" % fmt)
+
+    rows = [[feature_value(case, mapping.get(n, n)) for n in names] for case in cases]
+    reference = {
+        "tolerance": args.tolerance,
+        "cases": [{"features": case, "score": s} for case, s in zip(cases, score(rows))],
+    }
+    if contribs is not None:
+        # One column per input, the bias last
+        reference["saabas"] = saabas
+        for case, row in zip(reference["cases"], contribs(rows)):
+            case["contributions"] = dict(zip(names, row[:-1]))
+            case["bias"] = row[-1]
+    json.dump(reference, sys.stdout, indent=2)
+    sys.stdout.write("\n")
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,141 @@
+"""Train the small XGBoost and LightGBM models the Go parsers are tested with.
+
+    python train_fixtures.py ../testdata/models
+
+writes one directory per fixture (xgboost-fixture, lightgbm-fixture,
+lightgbm-stumps-fixture) holding the model export, a manifest, the cases and
+the reference scores and contributions, which export_reference.py computes
+with the library that trained the model. The stumps model has single split
+trees, where LightGBM's TreeSHAP contributions equal the path attribution the
+Go scorer computes, so its contributions are compared one for one.
+model_test.go loads every manifest under testdata/models and fails if the Go
+scorer disagrees with the reference. Re-run this after upgrading either
+library and commit the result.
+
+The data is synthetic and seeded: the point is real library output with
+missing values and boolean inputs, not a useful model. Requires numpy,
+PyYAML, xgboost and lightgbm.
+"""
+import argparse
+import json
+import os
+import subprocess
+import sys
+
+import numpy as np
+import yaml
+
+# model input name -> enriched feature
+FEATURES = {
+    "amount": "transaction.amount",
+    "error_balance_orig": "balance.error_balance_orig",
+    "orig_drained": "balance.orig_drained",
+    "txn_count_2h": "ip_signals.txn_count",
+}
+NAMES = list(FEATURES)
+
+
+def training_data(rng, n=2000):
+    amount = rng.lognormal(6, 1.5, n)
+    error = rng.normal(0, 50, n)
+    error[rng.random(n) < 0.1] = np.nan
+    drained = (rng.random(n) < 0.2).astype(float)
+    count = rng.poisson(3, n).astype(float)
+    z = -4 + 0.0004 * amount + 2.5 * drained + 0.2 * count + 0.01 * np.nan_to_num(np.abs(error))
+    label = (rng.random(n) < 1 / (1 + np.exp(-z))).astype(int)
+    return np.column_stack([amount, error, drained, count]), label
+
+
+def cases(rng, n=25):
+    out = []
+    for i in range(n):
+        case = {
+            "transaction.amount": round(float(rng.lognormal(6, 1.5)), 2),
+            "balance.error_balance_orig": round(float(rng.normal(0, 50)), 2),
+            "balance.orig_drained": bool(rng.random() < 0.3),
+            "ip_signals.txn_count": int(rng.poisson(3)),
+        }
+        # Every few cases leaves a feature out, so missing value routing is covered
+        if i % 5 == 0:
+            del case["balance.error_balance_orig"]
+        if i % 7 == 0:
+            del case["ip_signals.txn_count"]
+        out.append(case)
+    return out
+
+
+def write_fixture(out_dir, name, fmt, model_file, extra_args):
+    directory = os.path.join(out_dir, name)
+    with open(os.path.join(directory, "manifest.yaml"), "w") as f:
+        yaml.safe_dump(
+            {
+                "version": name,
+                "format": fmt,
+                "file": model_file,
+                "features": FEATURES,
+                "reference": "reference.json",
+                "metadata": {"description": "Test fixture written by models/train_fixtures.py"},
+            },
+            f,
+            sort_keys=False,
+        )
+    with open(os.path.join(directory, "reference.json"), "w") as f:
+        subprocess.run(
+            [
+                sys.executable,
+                os.path.join(os.path.dirname(os.path.abspath(__file__)), "export_reference.py"),
+                os.path.join(directory, "manifest.yaml"),
+                os.path.join(directory, "cases.json"),
+                *extra_args,
+            ],
+            stdout=f,
+            check=True,
+        )
+
+
+def main():
+    parser = argparse.ArgumentParser()
+    parser.add_argument("out_dir")
+    args = parser.parse_args()
+
+    import lightgbm
+    import xgboost
+
+    rng = np.random.default_rng(42)
+    x, y = training_data(rng)
+    test_cases = cases(rng)
+
+    for name in ("xgboost-fixture", "lightgbm-fixture", "lightgbm-stumps-fixture"):
+        os.makedirs(os.path.join(args.out_dir, name), exist_ok=True)
+        with open(os.path.join(args.out_dir, name, "cases.json"), "w") as f:
+            json.dump(test_cases, f, indent=2)
+
+    booster = xgboost.train(
+        {"objective": "binary:logistic", "max_depth": 3, "eta": 0.3, "seed": 42},
+        xgboost.DMatrix(x, label=y, feature_names=NAMES, missing=np.nan),
+        num_boost_round=5,
+    )
+    booster.save_model(os.path.join(args.out_dir, "xgboost-fixture", "fraud_xgb.json"))
+    write_fixture(args.out_dir, "xgboost-fixture", "xgboost", "fraud_xgb.json", [])
+
+    for name, num_leaves in (("lightgbm-fixture", 7), ("lightgbm-stumps-fixture", 2)):
+        lgbm_dir = os.path.join(args.out_dir, name)
+        model = lightgbm.train(
+            {"objective": "binary", "num_leaves": num_leaves, "learning_rate": 0.3, "seed": 42, "verbose": -1},
+            lightgbm.Dataset(x, label=y, feature_name=NAMES),
+            num_boost_round=5,
+        )
+        model.save_model(os.path.join(lgbm_dir, "model.txt"))
+        with open(os.path.join(lgbm_dir, "fraud_lgbm.json"), "w") as f:
+            json.dump(model.dump_model(), f)
+        write_fixture(
+            args.out_dir,
+            name,
+            "lightgbm",
+            "fraud_lgbm.json",
+            ["--lightgbm-model", os.path.join(lgbm_dir, "model.txt")],
+        )
+
+
+if __name__ == "__main__":
+    main()
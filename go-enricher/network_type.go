@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// network_type classifies the IP's network by ASN number from a curated file
+// (ASN_CATEGORIES_FILE), falling back to organisation name keywords for ASNs
+// the file doesn't list. Matching by number keeps e.g. Google Fiber
+// (residential) apart from Google Cloud. The file is re-read every
+// ASN_CATEGORIES_RELOAD_SECONDS.
+const (
+	defaultASNCategoriesFile   = "asn_categories.yaml"
+	defaultASNCategoriesReload = 5 * time.Minute
+
+	networkTypeCloud       = "cloud"
+	networkTypeHosting     = "hosting"
+	networkTypeCDN         = "cdn"
+	networkTypeMobile      = "mobile"
+	networkTypeResidential = "residential"
+	networkTypeBusiness    = "business"
+	networkTypeEducation   = "education"
+	networkTypeUnknown     = "unknown"
+)
+
+var validNetworkTypes = map[string]bool{
+	networkTypeCloud: true, networkTypeHosting: true, networkTypeCDN: true,
+	networkTypeMobile: true, networkTypeResidential: true,
+	networkTypeBusiness: true, networkTypeEducation: true,
+}
+
+// isDatacenterType reports whether traffic from the network type is served
+// from a datacenter rather than an end user connection (GeoData.is_hosting)
+func isDatacenterType(networkType string) bool {
+	return networkType == networkTypeCloud || networkType == networkTypeHosting || networkType == networkTypeCDN
+}
+
+type asnCategoriesFile struct {
+	Categories map[string][]uint32 `yaml:"categories"`
+}
+
+type networkClassifier struct {
+	byASN   atomic.Pointer[map[uint32]string]
+	path    string
+	version fileVersion
+}
+
+func newNetworkClassifier() *networkClassifier {
+	c := &networkClassifier{path: os.Getenv("ASN_CATEGORIES_FILE")}
+	if c.path == "" {
+		c.path = defaultASNCategoriesFile
+	}
+	if _, err := c.reload(); err != nil {
+		log.Printf("ASN categories not loaded, using keyword matching only: %v", err)
+	}
+	return c
+}
+
+// Classify returns the network type for asn ("AS15169" as on GeoData) and isp
+func (c *networkClassifier) Classify(asn string, isp string) string {
+	if byASN := c.byASN.Load(); byASN != nil {
+		if number, err := strconv.ParseUint(strings.TrimPrefix(asn, "AS"), 10, 32); err == nil {
+			if networkType, ok := (*byASN)[uint32(number)]; ok {
+				networkTypeMetrics.Add(networkType, 1)
+				return networkType
+			}
+		}
+	}
+	networkTypeMetrics.Add("keyword_fallback", 1)
+	if isHostingProvider(isp) {
+		return networkTypeHosting
+	}
+	return networkTypeUnknown
+}
+
+// reload re-reads the file if it changed
+func (c *networkClassifier) reload() (bool, error) {
+	version, err := statVersion(c.path)
+	if err != nil {
+		return false, err
+	}
+	if c.byASN.Load() != nil && version == c.version {
+		return false, nil
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return false, err
+	}
+	var file asnCategoriesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return false, fmt.Errorf("parsing %s: %w", c.path, err)
+	}
+
+	byASN := make(map[uint32]string)
+	for category, asns := range file.Categories {
+		if !validNetworkTypes[category] {
+			return false, fmt.Errorf("%s: unknown category %q", c.path, category)
+		}
+		for _, asn := range asns {
+			if previous, ok := byASN[asn]; ok && previous != category {
+				return false, fmt.Errorf("%s: AS%d listed as both %s and %s", c.path, asn, previous, category)
+			}
+			byASN[asn] = category
+		}
+	}
+	c.byASN.Store(&byASN)
+	c.version = version
+	log.Printf("Loaded %d ASN categories from %s", len(byASN), c.path)
+	return true, nil
+}
+
+// watch polls the file every ASN_CATEGORIES_RELOAD_SECONDS until ctx is cancelled
+func (c *networkClassifier) watch(ctx context.Context) {
+	pollReload(ctx, "ASN_CATEGORIES_RELOAD_SECONDS", defaultASNCategoriesReload, func() {
+		if _, err := c.reload(); err != nil {
+			log.Printf("ASN categories reload failed, keeping the loaded mapping: %v", err)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
 	"strconv"
 	"time"
@@ -13,28 +14,94 @@ import (
 )
 
 
-func getGeoFromRedis(client *redis.ClusterClient, ctx context.Context, ip string) (*GeoData, string, error) {
-	geoIp := "geo:" + ip
-	value, err := client.Get(ctx, geoIp).Result()
-	if err == redis.Nil {
-		// Entry not found
+// Geo results are cached per network ("geonet:<version>:<cidr>") rather than
+// per IP, so any address inside a cached range hits. The prefix lengths in use
+// are kept in the geonet:<version>:prefix_lengths set (members like "4/24",
+// "6/48") so a lookup only probes lengths that can exist. version is the geo
+// provider's data version: after a database reload every instance moves to new
+// keys, and the old ones expire with GEO_TTL_HOURS.
+func geoNetKey(version string, network netip.Prefix) string {
+	return "geonet:" + version + ":" + network.String()
+}
+
+func geoPrefixLengthsKey(version string) string {
+	return "geonet:" + version + ":prefix_lengths"
+}
+
+type cachedGeo struct {
+	GeoData
+	SeededBy string `json:"seeded_by"` // IP whose lookup populated the entry
+}
+
+// getGeoFromRedis probes the candidate networks containing ip, longest prefix first,
+// in a single pipeline. Status is "HIT" when the entry was seeded by this same IP,
+// "NETWORK_HIT" when another address in the range seeded it, or "MISS".
+func getGeoFromRedis(client *redis.ClusterClient, ctx context.Context, version string, ip string, prefixLens []int) (*GeoData, string, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, "INVALID_IP", err
+	}
+	addr = addr.Unmap()
+
+	var keys []string
+	for _, bits := range prefixLens {
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, geoNetKey(version, prefix))
+	}
+	if len(keys) == 0 {
 		return nil, "MISS", nil
-	} else if err != nil {
+	}
+
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
 		// Some issue
 		return nil, "REDDIS_ISSUE", err
 	}
-	geo := &GeoData{}
-	err = json.Unmarshal([]byte(value), geo)
-	if err != nil {
-		return nil, "UNMARSHALING_ISSUE", err
-	}
 
-	return geo, "HIT", nil
+	for _, cmd := range cmds {
+		value, err := cmd.Result()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, "REDDIS_ISSUE", err
+		}
+		cached := &cachedGeo{}
+		if err := json.Unmarshal([]byte(value), cached); err != nil {
+			return nil, "UNMARSHALING_ISSUE", err
+		}
+		if cached.SeededBy == ip {
+			return &cached.GeoData, "HIT", nil
+		}
+		return &cached.GeoData, "NETWORK_HIT", nil
+	}
+	// Entry not found
+	return nil, "MISS", nil
 }
 
-func setGeoToRedis(client *redis.ClusterClient, ctx context.Context, ip string, geodata GeoData) (string, error) {
-	geoIp := "geo:" + ip
-	geoJson, err := json.Marshal(geodata)
+// setGeoToRedis caches geodata for its network. Without a network from the
+// geo database the entry covers just this address (/32 or /128).
+func setGeoToRedis(client *redis.ClusterClient, ctx context.Context, version string, ip string, geodata GeoData) (string, error) {
+	network, err := netip.ParsePrefix(geodata.Network)
+	if err != nil {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return "INVALID_IP", err
+		}
+		addr = addr.Unmap()
+		network = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	network = network.Masked()
+
+	geoJson, err := json.Marshal(cachedGeo{GeoData: geodata, SeededBy: ip})
 	if err != nil {
 		return "MARSHAL_ISSUE", err
 	}
@@ -44,13 +111,31 @@ func setGeoToRedis(client *redis.ClusterClient, ctx context.Context, ip string,
 			ttlHours = parsed
 		}
 	}
-	err = client.Set(ctx, geoIp, geoJson, time.Duration(ttlHours)*time.Hour).Err()
+	err = client.Set(ctx, geoNetKey(version, network), geoJson, time.Duration(ttlHours)*time.Hour).Err()
+	if err != nil {
+		return "REDIS_SET_FAILED", err
+	}
+	err = client.SAdd(ctx, geoPrefixLengthsKey(version), prefixLengthMember(network)).Err()
 	if err != nil {
 		return "REDIS_SET_FAILED", err
 	}
 	return "REDIS_SET_SUCCESS", nil
 }
 
+func prefixLengthMember(network netip.Prefix) string {
+	family := "4"
+	if network.Addr().Is6() {
+		family = "6"
+	}
+	return family + "/" + strconv.Itoa(network.Bits())
+}
+
+func getGeoPrefixLengths(client *redis.ClusterClient, ctx context.Context, version string) ([]string, error) {
+	return client.SMembers(ctx, geoPrefixLengthsKey(version)).Result()
+}
+
+// Fraud state is keyed "fraud:<subject>", where subject is an IP or, for IPv6,
+// the aggregation prefix in CIDR form.
 func getFraudFromRedis(client *redis.ClusterClient, ctx context.Context, ip string) (*FraudSignals, string, error) {
 	fraudKey := "fraud:" + ip
 	value, err := client.Get(ctx, fraudKey).Result()
@@ -70,14 +155,21 @@ func getFraudFromRedis(client *redis.ClusterClient, ctx context.Context, ip stri
 	return fraud, "HIT", nil
 }
 
-func updateFraudInRedis(client *redis.ClusterClient, ctx context.Context, ip string, amount float64) (*FraudSignals, error) {
+// The signals cover a subject's activity until it goes quiet for
+// fraudStateWindow of event time, at which point they start over.
+const fraudStateWindow = 2 * time.Hour
+
+// updateFraudInRedis adds a transaction that happened at `at` (event time) to
+// the subject's signals. Out of order transactions widen first_seen/last_seen
+// rather than moving them back.
+func updateFraudInRedis(client *redis.ClusterClient, ctx context.Context, ip string, amount float64, at time.Time) (*FraudSignals, error) {
 	fraudKey := "fraud:" + ip	
 	fraud, status, err := getFraudFromRedis(client, ctx, ip)
 	
-	if status == "MISS" {
+	if status == "MISS" || (err == nil && at.Sub(fraud.LastSeen) > fraudStateWindow) {
 		fraud = &FraudSignals{
-			FirstSeen:      time.Now(),
-			LastSeen:       time.Now(),
+			FirstSeen:      at,
+			LastSeen:       at,
 			TxnCount:       1,
 			TotalAmount:    amount,
 			AmountVelocity: 0,
@@ -90,11 +182,16 @@ func updateFraudInRedis(client *redis.ClusterClient, ctx context.Context, ip str
 		return nil, fmt.Errorf("redis get failed: %w", err)
 		
 	} else {		
-		fraud.LastSeen = time.Now()
+		if at.Before(fraud.FirstSeen) {
+			fraud.FirstSeen = at
+		}
+		if at.After(fraud.LastSeen) {
+			fraud.LastSeen = at
+		}
 		fraud.TxnCount++
 		fraud.TotalAmount += amount
 
-		duration := time.Since(fraud.FirstSeen).Hours()
+		duration := fraud.LastSeen.Sub(fraud.FirstSeen).Hours()
 		if duration > 0 {
 			fraud.AmountVelocity = fraud.TotalAmount / duration
 		}
@@ -113,7 +210,8 @@ func updateFraudInRedis(client *redis.ClusterClient, ctx context.Context, ip str
 		return nil, fmt.Errorf("marshal failed: %w", err)
 	}
 
-	err = client.Set(ctx, fraudKey, fraudJSON, 2*time.Hour).Err()
+	// The TTL only garbage collects idle subjects; the window itself is in event time
+	err = client.Set(ctx, fraudKey, fraudJSON, fraudStateWindow).Err()
 	if err != nil {
 		return nil, fmt.Errorf("redis set failed: %w", err)
 	}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Reloadable inputs (rules, decision policy, sequence patterns, ASN
+// categories, models, geo and reputation databases) are polled on an interval
+// set by their own *_RELOAD_SECONDS variable. Each reload swaps the new data
+// in atomically only once it has loaded in full: input that fails to load
+// never replaces what is already in service.
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func statVersion(path string) (fileVersion, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}, err
+	}
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// pollReload calls reload every envVar seconds (fallback when unset or
+// invalid) until ctx is cancelled
+func pollReload(ctx context.Context, envVar string, fallback time.Duration, reload func()) {
+	interval := fallback
+	if env := os.Getenv(envVar); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			interval = time.Duration(parsed) * time.Second
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reload()
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/oschwald/maxminddb-golang"
+
+	pb "fraud-enricher/pb"
+)
+
+// IP reputation: explicit anonymity flags instead of guessing from the ISP
+// name. Two optional sources, either or both may be present:
+//
+//   - the GeoIP2 Anonymous IP database (ANON_IP_DB)
+//   - local lists with one IP or CIDR per line, # comments allowed:
+//     TOR_EXIT_LIST, VPN_LIST, PUBLIC_PROXY_LIST, RESIDENTIAL_PROXY_LIST
+//
+// All sources are checked for changes every REPUTATION_RELOAD_SECONDS and a
+// changed set is loaded into a new snapshot that replaces the old one
+// atomically. A source that is missing is simply skipped.
+const (
+	defaultAnonIPDBPath             = "/data/geoip/GeoIP2-Anonymous-IP.mmdb"
+	defaultReputationReloadInterval = 5 * time.Minute
+)
+
+type reputationList struct {
+	env  string
+	flag func(*pb.AnonymitySignals)
+}
+
+var reputationLists = []reputationList{
+	{"TOR_EXIT_LIST", func(a *pb.AnonymitySignals) { a.IsTor = true }},
+	{"VPN_LIST", func(a *pb.AnonymitySignals) { a.IsVpn = true }},
+	{"PUBLIC_PROXY_LIST", func(a *pb.AnonymitySignals) { a.IsPublicProxy = true }},
+	{"RESIDENTIAL_PROXY_LIST", func(a *pb.AnonymitySignals) { a.IsResidentialProxy = true }},
+}
+
+// reputationSnapshot is one loaded set of sources. Lookups hold the read lock
+// so the Anonymous IP reader is closed only after they finish.
+type reputationSnapshot struct {
+	mu     sync.RWMutex
+	closed bool
+	anonDB *maxminddb.Reader
+	lists  []*prefixTrie[struct{}] // parallel to reputationLists, nil if not configured
+}
+
+func (s *reputationSnapshot) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.anonDB != nil {
+		s.anonDB.Close()
+	}
+}
+
+type reputationChecker struct {
+	current  atomic.Pointer[reputationSnapshot]
+	anonPath string
+	paths    []string // parallel to reputationLists
+	versions map[string]fileVersion
+}
+
+func newReputationChecker() *reputationChecker {
+	c := &reputationChecker{anonPath: os.Getenv("ANON_IP_DB")}
+	if c.anonPath == "" {
+		c.anonPath = defaultAnonIPDBPath
+	}
+	for _, list := range reputationLists {
+		c.paths = append(c.paths, os.Getenv(list.env))
+	}
+	if _, err := c.reload(); err != nil {
+		log.Printf("IP reputation sources failed to load, anonymity flags stay false until they do: %v", err)
+	}
+	return c
+}
+
+// Check returns the anonymity flags for ip from every loaded source
+func (c *reputationChecker) Check(ip string) *pb.AnonymitySignals {
+	signals := &pb.AnonymitySignals{}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return signals
+	}
+	addr = addr.Unmap()
+
+	for {
+		snap := c.current.Load()
+		if snap == nil {
+			return signals
+		}
+		snap.mu.RLock()
+		if snap.closed {
+			snap.mu.RUnlock()
+			continue
+		}
+		if snap.anonDB != nil {
+			var record geoip2.AnonymousIP
+			if err := snap.anonDB.Lookup(net.IP(addr.AsSlice()), &record); err != nil {
+				reputationMetrics.Add("lookup_errors", 1)
+			} else {
+				signals.IsTor = record.IsTorExitNode
+				signals.IsVpn = record.IsAnonymousVPN
+				signals.IsPublicProxy = record.IsPublicProxy
+				signals.IsResidentialProxy = record.IsResidentialProxy
+			}
+		}
+		for i, trie := range snap.lists {
+			if trie == nil {
+				continue
+			}
+			if _, _, ok := trie.Lookup(addr); ok {
+				reputationLists[i].flag(signals)
+			}
+		}
+		snap.mu.RUnlock()
+		break
+	}
+	signals.IsAnonymous = signals.IsTor || signals.IsVpn || signals.IsPublicProxy || signals.IsResidentialProxy
+	return signals
+}
+
+// reload builds a new snapshot if any source file changed, appeared or went away
+func (c *reputationChecker) reload() (bool, error) {
+	versions := make(map[string]fileVersion)
+	for _, path := range append([]string{c.anonPath}, c.paths...) {
+		if path == "" {
+			continue
+		}
+		if v, err := statVersion(path); err == nil {
+			versions[path] = v
+		}
+	}
+	if c.current.Load() != nil && sameVersions(versions, c.versions) {
+		return false, nil
+	}
+
+	snap := &reputationSnapshot{lists: make([]*prefixTrie[struct{}], len(reputationLists))}
+	if _, ok := versions[c.anonPath]; ok {
+		db, err := maxminddb.Open(c.anonPath)
+		if err != nil {
+			reputationMetrics.Add("reload_errors", 1)
+			return false, fmt.Errorf("opening %s: %w", c.anonPath, err)
+		}
+		snap.anonDB = db
+		reputationMetrics.Set("anon_db_build_epoch", intVar(int64(db.Metadata.BuildEpoch)))
+	}
+	for i, path := range c.paths {
+		if _, ok := versions[path]; !ok {
+			continue
+		}
+		trie, err := loadIPList(path)
+		if err != nil {
+			if snap.anonDB != nil {
+				snap.anonDB.Close()
+			}
+			reputationMetrics.Add("reload_errors", 1)
+			return false, err
+		}
+		snap.lists[i] = trie
+		reputationMetrics.Set(strings.ToLower(reputationLists[i].env)+"_entries", intVar(int64(trie.Len())))
+	}
+
+	c.versions = versions
+	previous := c.current.Swap(snap)
+	log.Printf("Loaded IP reputation sources: %s", c.describe(snap))
+	if previous != nil {
+		reputationMetrics.Add("reloads", 1)
+		go previous.close()
+	}
+	return true, nil
+}
+
+func sameVersions(a, b map[string]fileVersion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, v := range a {
+		if b[path] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *reputationChecker) describe(snap *reputationSnapshot) string {
+	var parts []string
+	if snap.anonDB != nil {
+		parts = append(parts, "anonymous IP db "+c.anonPath)
+	}
+	for i, trie := range snap.lists {
+		if trie != nil {
+			parts = append(parts, fmt.Sprintf("%s (%d entries)", reputationLists[i].env, trie.Len()))
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// loadIPList reads one IP or CIDR per line; blank lines and # comments are skipped
+func loadIPList(path string) (*prefixTrie[struct{}], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	trie := newPrefixTrie[struct{}]()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(entry, '#'); i >= 0 {
+			entry = strings.TrimSpace(entry[:i])
+		}
+		if entry == "" {
+			continue
+		}
+		var network netip.Prefix
+		if strings.Contains(entry, "/") {
+			network, err = netip.ParsePrefix(entry)
+		} else {
+			var addr netip.Addr
+			addr, err = netip.ParseAddr(entry)
+			addr = addr.Unmap()
+			network = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		if err := trie.Insert(network.Masked(), struct{}{}); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return trie, nil
+}
+
+// watch re-checks the sources every REPUTATION_RELOAD_SECONDS until ctx is cancelled
+func (c *reputationChecker) watch(ctx context.Context) {
+	pollReload(ctx, "REPUTATION_RELOAD_SECONDS", defaultReputationReloadInterval, func() {
+		if _, err := c.reload(); err != nil {
+			log.Printf("IP reputation reload failed, keeping the loaded sources: %v", err)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "fraud-enricher/pb"
+)
+
+// explainExpr returns the conditions that made a matching expression true:
+// every comparison under && and the true branches of ||. A comparison whose
+// sides are both computed (e.g. a > b * 2) is reported with the left side
+// rendered as the feature.
+func explainExpr(node exprNode, features map[string]interface{}) []*pb.ReasonCondition {
+	var conditions []*pb.ReasonCondition
+	collectConditions(node, features, &conditions)
+	return conditions
+}
+
+func collectConditions(node exprNode, features map[string]interface{}, out *[]*pb.ReasonCondition) {
+	if matched, err := node.eval(features); err != nil || matched != true {
+		return
+	}
+	switch n := node.(type) {
+	case *binaryNode:
+		switch n.op {
+		case "&&", "||":
+			collectConditions(n.left, features, out)
+			collectConditions(n.right, features, out)
+			return
+		case "==", "!=", "<", "<=", ">", ">=":
+			left, op, right := n.left, n.op, n.right
+			if _, isLiteral := left.(*literalNode); isLiteral {
+				// 50000 < x reads as x > 50000
+				left, right = right, left
+				op = flippedComparison[op]
+			}
+			condition := &pb.ReasonCondition{Feature: exprString(left), Operator: op}
+			value, _ := left.eval(features)
+			threshold, _ := right.eval(features)
+			setConditionValue(condition, value, false)
+			setConditionValue(condition, threshold, true)
+			*out = append(*out, condition)
+			return
+		}
+	case *inNode:
+		condition := &pb.ReasonCondition{Feature: exprString(n.operand), Operator: "in", ThresholdText: exprString(&literalList{n.list})}
+		value, _ := n.operand.eval(features)
+		setConditionValue(condition, value, false)
+		*out = append(*out, condition)
+		return
+	case *unaryNode:
+		if field, ok := n.operand.(*fieldNode); ok && n.op == "!" {
+			*out = append(*out, &pb.ReasonCondition{Feature: field.name, Operator: "=="})
+			return
+		}
+	}
+	// A bare boolean (anonymity.is_tor) or anything else that is true as a whole
+	*out = append(*out, &pb.ReasonCondition{Feature: exprString(node), Operator: "==", Value: 1, Threshold: 1})
+}
+
+var flippedComparison = map[string]string{"==": "==", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+
+func setConditionValue(condition *pb.ReasonCondition, value interface{}, threshold bool) {
+	var number float64
+	var text string
+	switch v := value.(type) {
+	case bool:
+		if v {
+			number = 1
+		}
+	case string:
+		text = v
+	default:
+		number, _ = toFloat(v)
+	}
+	if threshold {
+		condition.Threshold, condition.ThresholdText = number, text
+	} else {
+		condition.Value, condition.ValueText = number, text
+	}
+}
+
+// literalList renders the list of an in expression
+type literalList struct {
+	items []exprNode
+}
+
+func (l *literalList) eval(features map[string]interface{}) (interface{}, error) {
+	return nil, fmt.Errorf("a list is not a value")
+}
+
+// exprString renders a compiled expression back to rule syntax
+func exprString(node exprNode) string {
+	switch n := node.(type) {
+	case *fieldNode:
+		return n.name
+	case *literalNode:
+		switch v := n.value.(type) {
+		case string:
+			return "'" + v + "'"
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		return fmt.Sprint(n.value)
+	case *unaryNode:
+		return n.op + exprString(n.operand)
+	case *binaryNode:
+		return "(" + exprString(n.left) + " " + n.op + " " + exprString(n.right) + ")"
+	case *inNode:
+		return exprString(n.operand) + " in " + exprString(&literalList{n.list})
+	case *literalList:
+		items := make([]string, len(n.items))
+		for i, item := range n.items {
+			items[i] = exprString(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprintf("%T", node)
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Small boolean expression language used by the rule engine, e.g.
+//   amount_velocity > 50000 && (txn_count_2h > 20 || country_code != 'US')
+// Identifiers are looked up by their JSON name on the enriched event.
+
+type exprNode interface {
+	eval(features map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+type fieldNode struct {
+	name string
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+type inNode struct {
+	operand exprNode
+	list    []exprNode
+}
+
+func (n *literalNode) eval(features map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *fieldNode) eval(features map[string]interface{}) (interface{}, error) {
+	value, ok := features[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", n.name)
+	}
+	return value, nil
+}
+
+func (n *unaryNode) eval(features map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(features)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator ! expects a bool, got %T", value)
+		}
+		return !b, nil
+	case "-":
+		f, ok := toFloat(value)
+		if !ok {
+			return nil, fmt.Errorf("operator - expects a number, got %T", value)
+		}
+		return -f, nil
+	}
+	return nil, fmt.Errorf("unknown unary operator %q", n.op)
+}
+
+func (n *binaryNode) eval(features map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(features)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short circuit the logical operators
+	if n.op == "&&" || n.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s expects bools, got %T", n.op, left)
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := n.right.eval(features)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s expects bools, got %T", n.op, right)
+		}
+		return r, nil
+	}
+
+	right, err := n.right.eval(features)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	}
+
+	l, lok := toFloat(left)
+	r, rok := toFloat(right)
+	if !lok || !rok {
+		ls, lsok := left.(string)
+		rs, rsok := right.(string)
+		if lsok && rsok {
+			switch n.op {
+			case "<":
+				return ls < rs, nil
+			case "<=":
+				return ls <= rs, nil
+			case ">":
+				return ls > rs, nil
+			case ">=":
+				return ls >= rs, nil
+			}
+		}
+		return nil, fmt.Errorf("operator %s expects numbers, got %T and %T", n.op, left, right)
+	}
+
+	switch n.op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return 0.0, nil
+		}
+		return l / r, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func (n *inNode) eval(features map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(features)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range n.list {
+		candidate, err := item.eval(features)
+		if err != nil {
+			return nil, err
+		}
+		if valuesEqual(value, candidate) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func valuesEqual(left, right interface{}) bool {
+	l, lok := toFloat(left)
+	r, rok := toFloat(right)
+	if lok && rok {
+		return l == r
+	}
+	return left == right
+}
+
+// Lexer
+
+type exprToken struct {
+	kind  string // "num", "str", "ident", "op", "eof"
+	text  string
+	value interface{}
+	pos   int
+}
+
+func tokenizeExpr(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.' || src[i] == '_' || src[i] == 'e' || src[i] == 'E' ||
+				((src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E'))) {
+				i++
+			}
+			text := src[start:i]
+			f, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", text, start)
+			}
+			tokens = append(tokens, exprToken{kind: "num", text: text, value: f, pos: start})
+
+		case c == '\'' || c == '"':
+			start := i
+			end := strings.IndexByte(src[i+1:], src[i])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			text := src[i+1 : i+1+end]
+			i += end + 2
+			tokens = append(tokens, exprToken{kind: "str", text: text, value: text, pos: start})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])) || src[i] == '_' || src[i] == '.') {
+				i++
+			}
+			text := src[start:i]
+			switch text {
+			case "true", "false":
+				tokens = append(tokens, exprToken{kind: "bool", text: text, value: text == "true", pos: start})
+			case "and":
+				tokens = append(tokens, exprToken{kind: "op", text: "&&", pos: start})
+			case "or":
+				tokens = append(tokens, exprToken{kind: "op", text: "||", pos: start})
+			case "not":
+				tokens = append(tokens, exprToken{kind: "op", text: "!", pos: start})
+			case "in":
+				tokens = append(tokens, exprToken{kind: "op", text: "in", pos: start})
+			default:
+				tokens = append(tokens, exprToken{kind: "ident", text: text, pos: start})
+			}
+
+		default:
+			start := i
+			two := ""
+			if i+1 < len(src) {
+				two = src[i : i+2]
+			}
+			switch two {
+			case "&&", "||", "==", "!=", "<=", ">=":
+				tokens = append(tokens, exprToken{kind: "op", text: two, pos: start})
+				i += 2
+				continue
+			}
+			switch c {
+			case '<', '>', '!', '+', '-', '*', '/', '(', ')', '[', ']', ',':
+				tokens = append(tokens, exprToken{kind: "op", text: string(c), pos: start})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected character %q at %d", c, start)
+			}
+		}
+	}
+	tokens = append(tokens, exprToken{kind: "eof", pos: len(src)})
+	return tokens, nil
+}
+
+// Recursive descent parser. Precedence from lowest to highest:
+//   ||, &&, comparison / in, + -, * /, unary ! -
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func compileExpr(src string) (exprNode, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q at %d", tok.text, tok.pos)
+	}
+	return node, nil
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "eof" {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) isOp(ops ...string) bool {
+	tok := p.peek()
+	if tok.kind != "op" {
+		return false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) expect(op string) error {
+	tok := p.next()
+	if tok.kind != "op" || tok.text != op {
+		return fmt.Errorf("expected %q at %d, got %q", op, tok.pos, tok.text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("==", "!=", "<", "<=", ">", ">=") {
+		op := p.next().text
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNode{op: op, left: left, right: right}, nil
+	}
+	if p.isOp("in") {
+		p.next()
+		if err := p.expect("["); err != nil {
+			return nil, err
+		}
+		var list []exprNode
+		for !p.isOp("]") {
+			item, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &inNode{operand: left, list: list}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+", "-") {
+		op := p.next().text
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*", "/") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.isOp("!", "-") {
+		op := p.next().text
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "num", "str", "bool":
+		return &literalNode{value: tok.value}, nil
+	case "ident":
+		return &fieldNode{name: tok.text}, nil
+	case "op":
+		if tok.text == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	}
+	if tok.kind == "eof" {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", tok.text, tok.pos)
+}
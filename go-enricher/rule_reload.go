@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultRulesRedisKey       = "rules:active"
+	defaultRulesReloadInterval = 30 * time.Second
+)
+
+// ruleEngine holds the live rule set and swaps it atomically when the source changes.
+// The source is either a file on disk or a Redis key holding the YAML/JSON document.
+type ruleEngine struct {
+	current atomic.Pointer[RuleSet]
+	hash    string
+
+	source   string // "file" or "redis"
+	path     string
+	redisKey string
+	client   *redis.ClusterClient
+}
+
+func newRuleEngine(client *redis.ClusterClient, ctx context.Context) (*ruleEngine, error) {
+	engine := &ruleEngine{
+		source:   os.Getenv("RULES_SOURCE"),
+		path:     os.Getenv("RULES_FILE"),
+		redisKey: os.Getenv("RULES_REDIS_KEY"),
+		client:   client,
+	}
+	if engine.source == "" {
+		engine.source = "file"
+	}
+	if engine.path == "" {
+		engine.path = defaultRulesFile
+	}
+	if engine.redisKey == "" {
+		engine.redisKey = defaultRulesRedisKey
+	}
+	if engine.source != "file" && engine.source != "redis" {
+		return nil, fmt.Errorf("unknown RULES_SOURCE %q", engine.source)
+	}
+
+	if _, err := engine.reload(ctx); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// Rules returns the rule set in effect. Callers should grab it once per transaction
+// so every rule and the stamped version come from the same set.
+func (e *ruleEngine) Rules() *RuleSet {
+	return e.current.Load()
+}
+
+func (e *ruleEngine) describeSource() string {
+	if e.source == "redis" {
+		return "redis key " + e.redisKey
+	}
+	return "file " + e.path
+}
+
+func (e *ruleEngine) read(ctx context.Context) ([]byte, error) {
+	if e.source == "redis" {
+		value, err := e.client.Get(ctx, e.redisKey).Result()
+		if err != nil {
+			return nil, fmt.Errorf("redis get %s: %w", e.redisKey, err)
+		}
+		return []byte(value), nil
+	}
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules file: %w", err)
+	}
+	return data, nil
+}
+
+// reload re-reads the source and swaps in the new rule set if the content changed
+func (e *ruleEngine) reload(ctx context.Context) (bool, error) {
+	data, err := e.read(ctx)
+	if err != nil {
+		return false, err
+	}
+	hash := contentHash(data)
+	if hash == e.hash {
+		return false, nil
+	}
+	rs, err := parseRuleSet(data)
+	if err != nil {
+		return false, err
+	}
+
+	shadow := 0
+	for _, rule := range rs.Rules {
+		if rule.State == ruleStateShadow {
+			shadow++
+		}
+	}
+	previous := e.current.Swap(rs)
+	e.hash = hash
+	if previous == nil {
+		log.Printf("Loaded rule set %s (%d rules, %d shadow) from %s", rs.Version, len(rs.Rules), shadow, e.describeSource())
+	} else {
+		log.Printf("Reloaded rule set %s -> %s (%d rules, %d shadow) from %s", previous.Version, rs.Version, len(rs.Rules), shadow, e.describeSource())
+	}
+	return true, nil
+}
+
+// watch polls the source until ctx is cancelled
+func (e *ruleEngine) watch(ctx context.Context) {
+	pollReload(ctx, "RULES_RELOAD_SECONDS", defaultRulesReloadInterval, func() {
+		if _, err := e.reload(ctx); err != nil {
+			log.Printf("Rule reload failed, keeping version %s: %v", e.Rules().Version, err)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	pb "fraud-enricher/pb"
+)
+
+const defaultRulesFile = "rules.yaml"
+
+// Rule is a single declarative check over the enriched event fields
+type Rule struct {
+	ID          string `yaml:"id" json:"id"`
+	Description string `yaml:"description" json:"description"`
+	Expression  string `yaml:"expression" json:"expression"`
+	Severity    string `yaml:"severity" json:"severity"`
+	ReasonCode  string `yaml:"reason_code" json:"reason_code"`
+	Action      string `yaml:"action" json:"action"`
+	State       string `yaml:"state" json:"state"`
+	AlertType   string `yaml:"alert_type" json:"alert_type"`
+
+	compiled exprNode
+}
+
+// Rule states. Shadow rules are evaluated and recorded on the event but never trigger actions.
+const (
+	ruleStateActive   = "active"
+	ruleStateShadow   = "shadow"
+	ruleStateDisabled = "disabled"
+)
+
+// Alert types. AML alerts (money laundering, e.g. structuring) go to their own
+// topic and are not fraud evidence for the decision stage.
+const (
+	alertTypeFraud = "fraud"
+	alertTypeAML   = "aml"
+)
+
+type RuleSet struct {
+	Version string  `yaml:"version" json:"version"`
+	Rules   []*Rule `yaml:"rules" json:"rules"`
+}
+
+var validSeverities = map[string]bool{"low": true, "medium": true, "high": true, "critical": true}
+var validActions = map[string]bool{"alert": true, "review": true, "block": true, "none": true}
+var validStates = map[string]bool{ruleStateActive: true, ruleStateShadow: true, ruleStateDisabled: true}
+var validAlertTypes = map[string]bool{alertTypeFraud: true, alertTypeAML: true}
+
+func loadRuleSet(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules file: %w", err)
+	}
+	return parseRuleSet(data)
+}
+
+// parseRuleSet accepts YAML or JSON (JSON is valid YAML) and compiles every expression.
+// A rule set without an explicit version gets one derived from its content.
+func parseRuleSet(data []byte) (*RuleSet, error) {
+	rs := &RuleSet{}
+	if err := yaml.Unmarshal(data, rs); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+	if rs.Version == "" {
+		rs.Version = "sha256:" + contentHash(data)[:12]
+	}
+
+	seen := make(map[string]bool)
+	for i, rule := range rs.Rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rule #%d has no id", i)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("duplicate rule id %q", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Severity == "" {
+			rule.Severity = "medium"
+		}
+		if !validSeverities[rule.Severity] {
+			return nil, fmt.Errorf("rule %s: invalid severity %q", rule.ID, rule.Severity)
+		}
+		if rule.Action == "" {
+			rule.Action = "alert"
+		}
+		if !validActions[rule.Action] {
+			return nil, fmt.Errorf("rule %s: invalid action %q", rule.ID, rule.Action)
+		}
+		if rule.ReasonCode == "" {
+			rule.ReasonCode = rule.ID
+		}
+		if rule.State == "" {
+			rule.State = ruleStateActive
+		}
+		if !validStates[rule.State] {
+			return nil, fmt.Errorf("rule %s: invalid state %q", rule.ID, rule.State)
+		}
+		if rule.AlertType == "" {
+			rule.AlertType = alertTypeFraud
+		}
+		if !validAlertTypes[rule.AlertType] {
+			return nil, fmt.Errorf("rule %s: invalid alert_type %q", rule.ID, rule.AlertType)
+		}
+
+		compiled, err := compileExpr(rule.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+		rule.compiled = compiled
+	}
+	return rs, nil
+}
+
+// Evaluate runs every enabled rule against the features and returns the matches,
+// split into active hits and shadow hits. A rule that fails to evaluate
+// (unknown field, type mismatch) is logged and skipped.
+func (rs *RuleSet) Evaluate(features map[string]interface{}) (hits []*pb.RuleHit, shadowHits []*pb.RuleHit) {
+	for _, rule := range rs.Rules {
+		if rule.State == ruleStateDisabled {
+			continue
+		}
+		result, err := rule.compiled.eval(features)
+		if err != nil {
+			log.Printf("Rule %s evaluation failed: %v", rule.ID, err)
+			continue
+		}
+		matched, ok := result.(bool)
+		if !ok {
+			log.Printf("Rule %s did not evaluate to a bool (got %T)", rule.ID, result)
+			continue
+		}
+		if !matched {
+			continue
+		}
+		hit := &pb.RuleHit{
+			RuleId:     rule.ID,
+			Severity:   rule.Severity,
+			ReasonCode: rule.ReasonCode,
+			Action:     rule.Action,
+			Conditions: explainExpr(rule.compiled, features),
+			AlertType:  rule.AlertType,
+		}
+		if rule.State == ruleStateShadow {
+			shadowHits = append(shadowHits, hit)
+		} else {
+			hits = append(hits, hit)
+		}
+	}
+	return hits, shadowHits
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
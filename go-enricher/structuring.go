@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	pb "fraud-enricher/pb"
+)
+
+// Structuring (smurfing): amounts split so each stays just under a reporting
+// threshold, e.g. several 9,500 transfers instead of one 28,500. A transaction
+// is in a threshold's band when
+//
+//	threshold * (1 - STRUCTURING_BAND_PERCENT/100) <= amount < threshold
+//
+// for any of STRUCTURING_THRESHOLDS (comma separated). In-band transactions
+// are kept per user and per destination account in st:{<scope>:<id>}, a
+// sorted set scored by event time (windowAmounts in aggregates.go).
+//
+// An in-band transaction with at least STRUCTURING_MIN_COUNT in-band
+// transactions (itself included) for the same threshold within
+// STRUCTURING_WINDOW_HOURS is structuring. It's a money laundering signal,
+// not fraud: the rules raise it as alert_type aml (alerts.go).
+//
+// At most structuringMaxEntries are kept per key, as for card testing.
+const (
+	defaultStructuringThresholds = "10000"
+	defaultStructuringBand       = 10.0
+	defaultStructuringWindow     = 24 * time.Hour
+	defaultStructuringMinCount   = 3
+
+	structuringMaxEntries = 500
+)
+
+type structuringConfig struct {
+	thresholds []float64 // ascending
+	band       float64   // fraction below each threshold
+	window     time.Duration
+	minCount   int
+}
+
+var structuringCfg = loadStructuringConfig()
+
+func loadStructuringConfig() structuringConfig {
+	cfg := structuringConfig{
+		band:     defaultStructuringBand / 100,
+		window:   defaultStructuringWindow,
+		minCount: defaultStructuringMinCount,
+	}
+	thresholds := os.Getenv("STRUCTURING_THRESHOLDS")
+	if thresholds == "" {
+		thresholds = defaultStructuringThresholds
+	}
+	for _, field := range strings.Split(thresholds, ",") {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || parsed <= 0 {
+			log.Printf("Ignoring invalid structuring threshold %q", field)
+			continue
+		}
+		cfg.thresholds = append(cfg.thresholds, parsed)
+	}
+	sort.Float64s(cfg.thresholds)
+	if env := os.Getenv("STRUCTURING_BAND_PERCENT"); env != "" {
+		if parsed, err := strconv.ParseFloat(env, 64); err == nil && parsed > 0 && parsed < 100 {
+			cfg.band = parsed / 100
+		}
+	}
+	if env := os.Getenv("STRUCTURING_WINDOW_HOURS"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			cfg.window = time.Duration(parsed) * time.Hour
+		}
+	}
+	if env := os.Getenv("STRUCTURING_MIN_COUNT"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			cfg.minCount = parsed
+		}
+	}
+	structuringMetrics.Set("thresholds", stringVar(strings.Trim(fmt.Sprint(cfg.thresholds), "[]")))
+	structuringMetrics.Set("window_seconds", intVar(int64(cfg.window/time.Second)))
+	return cfg
+}
+
+func (c structuringConfig) inBand(amount float64, threshold float64) bool {
+	return amount >= threshold*(1-c.band) && amount < threshold
+}
+
+func (c structuringConfig) anyBand(amount float64) bool {
+	for _, threshold := range c.thresholds {
+		if c.inBand(amount, threshold) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateStructuring records the transaction against scope/id if it is in a
+// band and returns the structuring signals over the window ending at `at`
+// (event time).
+func updateStructuring(client *redis.ClusterClient, ctx context.Context, scope string, id string, txnID string, amount float64, at time.Time) (*pb.StructuringSignals, error) {
+	signals := &pb.StructuringSignals{WindowSeconds: int64(structuringCfg.window / time.Second)}
+	if len(structuringCfg.thresholds) == 0 {
+		return signals, nil
+	}
+
+	key := fmt.Sprintf("st:{%s:%s}", scope, id)
+	amounts, err := windowAmounts(client, ctx, key, structuringCfg.anyBand(amount), txnID, amount, at, structuringCfg.window, structuringMaxEntries)
+	if err != nil {
+		structuringMetrics.Add("state_errors", 1)
+		return nil, fmt.Errorf("structuring %s pipeline failed: %w", key, err)
+	}
+
+	// Report the threshold this transaction is in the band of, if any, with
+	// the most transactions in its band
+	for _, threshold := range structuringCfg.thresholds {
+		inBand := structuringCfg.inBand(amount, threshold)
+		var count int64
+		var total float64
+		for _, value := range amounts {
+			if structuringCfg.inBand(value, threshold) {
+				count++
+				total += value
+			}
+		}
+		if signals.Threshold != 0 && (signals.InBand && !inBand || signals.InBand == inBand && count < signals.BandTxnCount) {
+			continue
+		}
+		signals.Threshold = threshold
+		signals.InBand = inBand
+		signals.BandTxnCount = count
+		signals.BandTxnTotal = total
+	}
+	signals.Structuring = signals.InBand && signals.BandTxnCount >= int64(structuringCfg.minCount)
+	if signals.Structuring {
+		structuringMetrics.Add(scope+"_flags", 1)
+	}
+	return signals, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	pb "fraud-enricher/pb"
+)
+
+// Per-user history. The countries a user has transacted from are kept in a
+// hash with the event time each was last seen; a country not seen for
+// USER_HISTORY_DAYS of event time drops out:
+//
+//	user:{<user_id>}:countries    HSET country_code <last seen unix millis>
+//
+// A country is new only for a user with history, so a user's first
+// transaction never counts as NEW_COUNTRY_FOR_USER. The key's TTL only
+// cleans up users who stopped transacting.
+const defaultUserHistory = 90 * 24 * time.Hour
+
+var userHistoryTTL = loadUserHistoryTTL()
+
+func loadUserHistoryTTL() time.Duration {
+	if env := os.Getenv("USER_HISTORY_DAYS"); env != "" {
+		if parsed, err := strconv.Atoi(env); err == nil && parsed > 0 {
+			return time.Duration(parsed) * 24 * time.Hour
+		}
+	}
+	return defaultUserHistory
+}
+
+// updateUserCountries records that the user transacted from countryCode at
+// `at` (event time) and reports whether the country is new. Transactions
+// without a country (no geo) leave the history alone.
+func updateUserCountries(client *redis.ClusterClient, ctx context.Context, userID string, countryCode string, at time.Time) (*pb.UserSignals, error) {
+	key := "user:{" + userID + "}:countries"
+	history, err := client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("user history %s read failed: %w", key, err)
+	}
+
+	var expired []string
+	signals := &pb.UserSignals{}
+	known := false
+	for country, lastSeen := range history {
+		millis, err := strconv.ParseInt(lastSeen, 10, 64)
+		if err != nil || at.Sub(time.UnixMilli(millis)) > userHistoryTTL {
+			expired = append(expired, country)
+			continue
+		}
+		signals.CountriesSeen++
+		if country == countryCode {
+			known = true
+		}
+	}
+	if countryCode == "" {
+		return signals, nil
+	}
+	if !known {
+		signals.NewCountry = signals.CountriesSeen > 0
+		signals.CountriesSeen++
+	}
+
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(expired) > 0 {
+			pipe.HDel(ctx, key, expired...)
+		}
+		// An out of order transaction doesn't move last seen back
+		if previous, err := strconv.ParseInt(history[countryCode], 10, 64); err != nil || at.UnixMilli() > previous {
+			pipe.HSet(ctx, key, countryCode, at.UnixMilli())
+		}
+		pipe.Expire(ctx, key, userHistoryTTL)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("user history %s pipeline failed: %w", key, err)
+	}
+	return signals, nil
+}
@@ -7,7 +7,10 @@ import (
 )
 
 
-func isHostingProvider(isp string) bool {
+// hostingKeywords is read once; HOSTING_KEYWORDS overrides the defaults
+var hostingKeywords = loadHostingKeywords()
+
+func loadHostingKeywords() []string {
 	keywordsEnv := os.Getenv("HOSTING_KEYWORDS")
 	var keywords []string
 	
@@ -18,13 +21,22 @@ func isHostingProvider(isp string) bool {
 			"amazon", "aws", "google", "azure", "microsoft",
 			"digitalocean", "ovh", "hetzner", "linode",
 			"vultr", "cloudflare", "hosting", "datacenter",
-			"vpn", "proxy", "colocation",
+			"colocation",
 		}
 	}
-	
+	for i := range keywords {
+		keywords[i] = strings.ToLower(strings.TrimSpace(keywords[i]))
+	}
+	return keywords
+}
+
+// isHostingProvider guesses from the organisation name. It is only the fallback
+// for ASNs missing from the curated categories (network_type.go); VPNs and
+// proxies are flagged from reputation sources (reputation.go), not by name.
+func isHostingProvider(isp string) bool {
 	ispLower := strings.ToLower(isp)
-	for _, keyword := range keywords {
-		if strings.Contains(ispLower, strings.TrimSpace(keyword)) {
+	for _, keyword := range hostingKeywords {
+		if keyword != "" && strings.Contains(ispLower, keyword) {
 			return true
 		}
 	}
@@ -35,8 +35,10 @@ type TransactionRequest struct {
 	NewBalanceDest          float64                `protobuf:"fixed64,10,opt,name=new_balance_dest,json=newBalanceDest,proto3" json:"new_balance_dest,omitempty"`
 	IsUnauthorizedOverdraft float64                `protobuf:"fixed64,11,opt,name=is_unauthorized_overdraft,json=isUnauthorizedOverdraft,proto3" json:"is_unauthorized_overdraft,omitempty"`
 	IpAddress               string                 `protobuf:"bytes,12,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
-	unknownFields           protoimpl.UnknownFields
-	sizeCache               protoimpl.SizeCache
+	// Receiving account (PaySim nameDest), empty when unknown
+	DestAccount   string `protobuf:"bytes,13,opt,name=dest_account,json=destAccount,proto3" json:"dest_account,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TransactionRequest) Reset() {
@@ -153,6 +155,13 @@ func (x *TransactionRequest) GetIpAddress() string {
 	return ""
 }
 
+func (x *TransactionRequest) GetDestAccount() string {
+	if x != nil {
+		return x.DestAccount
+	}
+	return ""
+}
+
 type IngestionResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -205,29 +214,1706 @@ func (x *IngestionResponse) GetMessage() string {
 	return ""
 }
 
-var File_proto_fraud_v1_fraud_proto protoreflect.FileDescriptor
+// Geo enrichment for the transaction IP (MaxMind City + ASN)
+type GeoData struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	City        string                 `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+	Country     string                 `protobuf:"bytes,2,opt,name=country,proto3" json:"country,omitempty"`
+	CountryCode string                 `protobuf:"bytes,3,opt,name=country_code,json=countryCode,proto3" json:"country_code,omitempty"`
+	Latitude    float64                `protobuf:"fixed64,4,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude   float64                `protobuf:"fixed64,5,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Asn         string                 `protobuf:"bytes,6,opt,name=asn,proto3" json:"asn,omitempty"`
+	Isp         string                 `protobuf:"bytes,7,opt,name=isp,proto3" json:"isp,omitempty"`
+	IsHosting   bool                   `protobuf:"varint,8,opt,name=is_hosting,json=isHosting,proto3" json:"is_hosting,omitempty"` // network_type is cloud, hosting or cdn
+	Network     string                 `protobuf:"bytes,9,opt,name=network,proto3" json:"network,omitempty"`                       // CIDR the geo record applies to
+	// cloud, hosting, cdn, mobile, residential, business, education or unknown
+	NetworkType   string `protobuf:"bytes,10,opt,name=network_type,json=networkType,proto3" json:"network_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_proto_fraud_v1_fraud_proto_rawDesc = "" +
-	"\n" +
-	"\x1aproto/fraud/v1/fraud.proto\x12\x05fraud\"\xbc\x03\n" +
-	"\x12TransactionRequest\x12%\n" +
-	"\x0etransaction_id\x18\x01 \x01(\tR\rtransactionId\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x16\n" +
-	"\x06amount\x18\x03 \x01(\x01R\x06amount\x12\x1c\n" +
-	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12\x19\n" +
-	"\bis_fraud\x18\x05 \x01(\bR\aisFraud\x12\x12\n" +
-	"\x04type\x18\x06 \x01(\tR\x04type\x12(\n" +
-	"\x10old_balance_orig\x18\a \x01(\x01R\x0eoldBalanceOrig\x12(\n" +
-	"\x10new_balance_orig\x18\b \x01(\x01R\x0enewBalanceOrig\x12(\n" +
-	"\x10old_balance_dest\x18\t \x01(\x01R\x0eoldBalanceDest\x12(\n" +
-	"\x10new_balance_dest\x18\n" +
-	" \x01(\x01R\x0enewBalanceDest\x12:\n" +
-	"\x19is_unauthorized_overdraft\x18\v \x01(\x01R\x17isUnauthorizedOverdraft\x12\x1d\n" +
-	"\n" +
-	"ip_address\x18\f \x01(\tR\tipAddress\"G\n" +
-	"\x11IngestionResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2X\n" +
+func (x *GeoData) Reset() {
+	*x = GeoData{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GeoData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GeoData) ProtoMessage() {}
+
+func (x *GeoData) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GeoData.ProtoReflect.Descriptor instead.
+func (*GeoData) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GeoData) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *GeoData) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *GeoData) GetCountryCode() string {
+	if x != nil {
+		return x.CountryCode
+	}
+	return ""
+}
+
+func (x *GeoData) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *GeoData) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *GeoData) GetAsn() string {
+	if x != nil {
+		return x.Asn
+	}
+	return ""
+}
+
+func (x *GeoData) GetIsp() string {
+	if x != nil {
+		return x.Isp
+	}
+	return ""
+}
+
+func (x *GeoData) GetIsHosting() bool {
+	if x != nil {
+		return x.IsHosting
+	}
+	return false
+}
+
+func (x *GeoData) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *GeoData) GetNetworkType() string {
+	if x != nil {
+		return x.NetworkType
+	}
+	return ""
+}
+
+// Windowed activity aggregates for one key (e.g. an IP) over the last 2h
+type FraudSignals struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	FirstSeen      int64                  `protobuf:"varint,1,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"` // unix millis
+	LastSeen       int64                  `protobuf:"varint,2,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`    // unix millis
+	TxnCount       int64                  `protobuf:"varint,3,opt,name=txn_count,json=txnCount,proto3" json:"txn_count,omitempty"`
+	TotalAmount    float64                `protobuf:"fixed64,4,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"`
+	AmountVelocity float64                `protobuf:"fixed64,5,opt,name=amount_velocity,json=amountVelocity,proto3" json:"amount_velocity,omitempty"` // total_amount per hour since first_seen
+	AvgAmount      float64                `protobuf:"fixed64,6,opt,name=avg_amount,json=avgAmount,proto3" json:"avg_amount,omitempty"`
+	MaxAmount      float64                `protobuf:"fixed64,7,opt,name=max_amount,json=maxAmount,proto3" json:"max_amount,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *FraudSignals) Reset() {
+	*x = FraudSignals{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FraudSignals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FraudSignals) ProtoMessage() {}
+
+func (x *FraudSignals) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FraudSignals.ProtoReflect.Descriptor instead.
+func (*FraudSignals) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *FraudSignals) GetFirstSeen() int64 {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return 0
+}
+
+func (x *FraudSignals) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+func (x *FraudSignals) GetTxnCount() int64 {
+	if x != nil {
+		return x.TxnCount
+	}
+	return 0
+}
+
+func (x *FraudSignals) GetTotalAmount() float64 {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return 0
+}
+
+func (x *FraudSignals) GetAmountVelocity() float64 {
+	if x != nil {
+		return x.AmountVelocity
+	}
+	return 0
+}
+
+func (x *FraudSignals) GetAvgAmount() float64 {
+	if x != nil {
+		return x.AvgAmount
+	}
+	return 0
+}
+
+func (x *FraudSignals) GetMaxAmount() float64 {
+	if x != nil {
+		return x.MaxAmount
+	}
+	return 0
+}
+
+// Anonymity flags from the GeoIP2 Anonymous IP database and local Tor exit,
+// VPN and proxy lists
+type AnonymitySignals struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	IsAnonymous        bool                   `protobuf:"varint,1,opt,name=is_anonymous,json=isAnonymous,proto3" json:"is_anonymous,omitempty"` // any of the below
+	IsTor              bool                   `protobuf:"varint,2,opt,name=is_tor,json=isTor,proto3" json:"is_tor,omitempty"`
+	IsVpn              bool                   `protobuf:"varint,3,opt,name=is_vpn,json=isVpn,proto3" json:"is_vpn,omitempty"`
+	IsPublicProxy      bool                   `protobuf:"varint,4,opt,name=is_public_proxy,json=isPublicProxy,proto3" json:"is_public_proxy,omitempty"`
+	IsResidentialProxy bool                   `protobuf:"varint,5,opt,name=is_residential_proxy,json=isResidentialProxy,proto3" json:"is_residential_proxy,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *AnonymitySignals) Reset() {
+	*x = AnonymitySignals{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnonymitySignals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnonymitySignals) ProtoMessage() {}
+
+func (x *AnonymitySignals) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnonymitySignals.ProtoReflect.Descriptor instead.
+func (*AnonymitySignals) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *AnonymitySignals) GetIsAnonymous() bool {
+	if x != nil {
+		return x.IsAnonymous
+	}
+	return false
+}
+
+func (x *AnonymitySignals) GetIsTor() bool {
+	if x != nil {
+		return x.IsTor
+	}
+	return false
+}
+
+func (x *AnonymitySignals) GetIsVpn() bool {
+	if x != nil {
+		return x.IsVpn
+	}
+	return false
+}
+
+func (x *AnonymitySignals) GetIsPublicProxy() bool {
+	if x != nil {
+		return x.IsPublicProxy
+	}
+	return false
+}
+
+func (x *AnonymitySignals) GetIsResidentialProxy() bool {
+	if x != nil {
+		return x.IsResidentialProxy
+	}
+	return false
+}
+
+// Balance consistency features (PaySim error terms)
+type BalanceSignals struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	ErrorBalanceOrig     float64                `protobuf:"fixed64,1,opt,name=error_balance_orig,json=errorBalanceOrig,proto3" json:"error_balance_orig,omitempty"`            // new_balance_orig + amount - old_balance_orig
+	ErrorBalanceDest     float64                `protobuf:"fixed64,2,opt,name=error_balance_dest,json=errorBalanceDest,proto3" json:"error_balance_dest,omitempty"`            // old_balance_dest + amount - new_balance_dest
+	OrigDrained          bool                   `protobuf:"varint,3,opt,name=orig_drained,json=origDrained,proto3" json:"orig_drained,omitempty"`                              // origin had funds and is now at zero
+	DestUnchanged        bool                   `protobuf:"varint,4,opt,name=dest_unchanged,json=destUnchanged,proto3" json:"dest_unchanged,omitempty"`                        // destination balance did not move
+	AmountExceedsBalance bool                   `protobuf:"varint,5,opt,name=amount_exceeds_balance,json=amountExceedsBalance,proto3" json:"amount_exceeds_balance,omitempty"` // amount > old_balance_orig
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *BalanceSignals) Reset() {
+	*x = BalanceSignals{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BalanceSignals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BalanceSignals) ProtoMessage() {}
+
+func (x *BalanceSignals) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BalanceSignals.ProtoReflect.Descriptor instead.
+func (*BalanceSignals) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BalanceSignals) GetErrorBalanceOrig() float64 {
+	if x != nil {
+		return x.ErrorBalanceOrig
+	}
+	return 0
+}
+
+func (x *BalanceSignals) GetErrorBalanceDest() float64 {
+	if x != nil {
+		return x.ErrorBalanceDest
+	}
+	return 0
+}
+
+func (x *BalanceSignals) GetOrigDrained() bool {
+	if x != nil {
+		return x.OrigDrained
+	}
+	return false
+}
+
+func (x *BalanceSignals) GetDestUnchanged() bool {
+	if x != nil {
+		return x.DestUnchanged
+	}
+	return false
+}
+
+func (x *BalanceSignals) GetAmountExceedsBalance() bool {
+	if x != nil {
+		return x.AmountExceedsBalance
+	}
+	return false
+}
+
+// Sliding window totals for a group of IPs, e.g. an IPv4 /24 or an ASN
+type AggregateSignals struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"` // "subnet:203.0.113.0/24", "asn:AS64500"
+	TxnCount      int64                  `protobuf:"varint,2,opt,name=txn_count,json=txnCount,proto3" json:"txn_count,omitempty"`
+	DistinctUsers int64                  `protobuf:"varint,3,opt,name=distinct_users,json=distinctUsers,proto3" json:"distinct_users,omitempty"` // HyperLogLog estimate
+	TotalAmount   float64                `protobuf:"fixed64,4,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"`
+	WindowSeconds int64                  `protobuf:"varint,5,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AggregateSignals) Reset() {
+	*x = AggregateSignals{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateSignals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateSignals) ProtoMessage() {}
+
+func (x *AggregateSignals) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateSignals.ProtoReflect.Descriptor instead.
+func (*AggregateSignals) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AggregateSignals) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *AggregateSignals) GetTxnCount() int64 {
+	if x != nil {
+		return x.TxnCount
+	}
+	return 0
+}
+
+func (x *AggregateSignals) GetDistinctUsers() int64 {
+	if x != nil {
+		return x.DistinctUsers
+	}
+	return 0
+}
+
+func (x *AggregateSignals) GetTotalAmount() float64 {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return 0
+}
+
+func (x *AggregateSignals) GetWindowSeconds() int64 {
+	if x != nil {
+		return x.WindowSeconds
+	}
+	return 0
+}
+
+// Per-user history across transactions
+type UserSignals struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NewCountry    bool                   `protobuf:"varint,1,opt,name=new_country,json=newCountry,proto3" json:"new_country,omitempty"`          // first transaction from this country, user seen before
+	CountriesSeen int64                  `protobuf:"varint,2,opt,name=countries_seen,json=countriesSeen,proto3" json:"countries_seen,omitempty"` // distinct countries in the history window, this one included
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserSignals) Reset() {
+	*x = UserSignals{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserSignals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserSignals) ProtoMessage() {}
+
+func (x *UserSignals) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserSignals.ProtoReflect.Descriptor instead.
+func (*UserSignals) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UserSignals) GetNewCountry() bool {
+	if x != nil {
+		return x.NewCountry
+	}
+	return false
+}
+
+func (x *UserSignals) GetCountriesSeen() int64 {
+	if x != nil {
+		return x.CountriesSeen
+	}
+	return 0
+}
+
+// Card testing: small transactions (at or below CARD_TESTING_MAX_AMOUNT)
+// from one IP, subnet or user within CARD_TESTING_WINDOW_SECONDS
+type CardTestingSignals struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	SmallTxnCount  int64                  `protobuf:"varint,1,opt,name=small_txn_count,json=smallTxnCount,proto3" json:"small_txn_count,omitempty"` // in the window, this one included if small
+	SmallTxnTotal  float64                `protobuf:"fixed64,2,opt,name=small_txn_total,json=smallTxnTotal,proto3" json:"small_txn_total,omitempty"`
+	MaxSmallAmount float64                `protobuf:"fixed64,3,opt,name=max_small_amount,json=maxSmallAmount,proto3" json:"max_small_amount,omitempty"`
+	Burst          bool                   `protobuf:"varint,4,opt,name=burst,proto3" json:"burst,omitempty"` // small_txn_count >= CARD_TESTING_MIN_BURST
+	// A larger amount after a burst: above the ceiling and at least
+	// CARD_TESTING_ESCALATION_RATIO times max_small_amount
+	Escalation      bool    `protobuf:"varint,5,opt,name=escalation,proto3" json:"escalation,omitempty"`
+	EscalationRatio float64 `protobuf:"fixed64,6,opt,name=escalation_ratio,json=escalationRatio,proto3" json:"escalation_ratio,omitempty"` // amount / max_small_amount, 0 without small transactions
+	WindowSeconds   int64   `protobuf:"varint,7,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CardTestingSignals) Reset() {
+	*x = CardTestingSignals{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CardTestingSignals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CardTestingSignals) ProtoMessage() {}
+
+func (x *CardTestingSignals) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CardTestingSignals.ProtoReflect.Descriptor instead.
+func (*CardTestingSignals) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CardTestingSignals) GetSmallTxnCount() int64 {
+	if x != nil {
+		return x.SmallTxnCount
+	}
+	return 0
+}
+
+func (x *CardTestingSignals) GetSmallTxnTotal() float64 {
+	if x != nil {
+		return x.SmallTxnTotal
+	}
+	return 0
+}
+
+func (x *CardTestingSignals) GetMaxSmallAmount() float64 {
+	if x != nil {
+		return x.MaxSmallAmount
+	}
+	return 0
+}
+
+func (x *CardTestingSignals) GetBurst() bool {
+	if x != nil {
+		return x.Burst
+	}
+	return false
+}
+
+func (x *CardTestingSignals) GetEscalation() bool {
+	if x != nil {
+		return x.Escalation
+	}
+	return false
+}
+
+func (x *CardTestingSignals) GetEscalationRatio() float64 {
+	if x != nil {
+		return x.EscalationRatio
+	}
+	return 0
+}
+
+func (x *CardTestingSignals) GetWindowSeconds() int64 {
+	if x != nil {
+		return x.WindowSeconds
+	}
+	return 0
+}
+
+// Structuring: repeated amounts just below a reporting threshold (e.g. many
+// 9,500s under 10,000) for one user or destination within
+// STRUCTURING_WINDOW_HOURS. Reports the configured threshold with the most
+// transactions in its band.
+type StructuringSignals struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Threshold     float64                `protobuf:"fixed64,1,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	BandTxnCount  int64                  `protobuf:"varint,2,opt,name=band_txn_count,json=bandTxnCount,proto3" json:"band_txn_count,omitempty"` // in the threshold's band, this one included
+	BandTxnTotal  float64                `protobuf:"fixed64,3,opt,name=band_txn_total,json=bandTxnTotal,proto3" json:"band_txn_total,omitempty"`
+	InBand        bool                   `protobuf:"varint,4,opt,name=in_band,json=inBand,proto3" json:"in_band,omitempty"` // this transaction is in the band
+	Structuring   bool                   `protobuf:"varint,5,opt,name=structuring,proto3" json:"structuring,omitempty"`     // in_band && band_txn_count >= STRUCTURING_MIN_COUNT
+	WindowSeconds int64                  `protobuf:"varint,6,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StructuringSignals) Reset() {
+	*x = StructuringSignals{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StructuringSignals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StructuringSignals) ProtoMessage() {}
+
+func (x *StructuringSignals) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StructuringSignals.ProtoReflect.Descriptor instead.
+func (*StructuringSignals) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StructuringSignals) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *StructuringSignals) GetBandTxnCount() int64 {
+	if x != nil {
+		return x.BandTxnCount
+	}
+	return 0
+}
+
+func (x *StructuringSignals) GetBandTxnTotal() float64 {
+	if x != nil {
+		return x.BandTxnTotal
+	}
+	return 0
+}
+
+func (x *StructuringSignals) GetInBand() bool {
+	if x != nil {
+		return x.InBand
+	}
+	return false
+}
+
+func (x *StructuringSignals) GetStructuring() bool {
+	if x != nil {
+		return x.Structuring
+	}
+	return false
+}
+
+func (x *StructuringSignals) GetWindowSeconds() int64 {
+	if x != nil {
+		return x.WindowSeconds
+	}
+	return 0
+}
+
+// One condition that made a rule or decision signal match: the feature, its
+// value and the threshold it was compared against. Numbers and bools (as 1/0)
+// go in value / threshold, strings in value_text / threshold_text.
+type ReasonCondition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Feature       string                 `protobuf:"bytes,1,opt,name=feature,proto3" json:"feature,omitempty"`   // enriched feature path, e.g. ip_signals.amount_velocity
+	Operator      string                 `protobuf:"bytes,2,opt,name=operator,proto3" json:"operator,omitempty"` // > >= < <= == != in
+	Value         float64                `protobuf:"fixed64,3,opt,name=value,proto3" json:"value,omitempty"`
+	Threshold     float64                `protobuf:"fixed64,4,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	ValueText     string                 `protobuf:"bytes,5,opt,name=value_text,json=valueText,proto3" json:"value_text,omitempty"`
+	ThresholdText string                 `protobuf:"bytes,6,opt,name=threshold_text,json=thresholdText,proto3" json:"threshold_text,omitempty"` // e.g. "['TRANSFER', 'CASH_OUT']" for in
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReasonCondition) Reset() {
+	*x = ReasonCondition{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReasonCondition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReasonCondition) ProtoMessage() {}
+
+func (x *ReasonCondition) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReasonCondition.ProtoReflect.Descriptor instead.
+func (*ReasonCondition) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ReasonCondition) GetFeature() string {
+	if x != nil {
+		return x.Feature
+	}
+	return ""
+}
+
+func (x *ReasonCondition) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *ReasonCondition) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *ReasonCondition) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *ReasonCondition) GetValueText() string {
+	if x != nil {
+		return x.ValueText
+	}
+	return ""
+}
+
+func (x *ReasonCondition) GetThresholdText() string {
+	if x != nil {
+		return x.ThresholdText
+	}
+	return ""
+}
+
+type RuleHit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RuleId        string                 `protobuf:"bytes,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	Severity      string                 `protobuf:"bytes,2,opt,name=severity,proto3" json:"severity,omitempty"`
+	ReasonCode    string                 `protobuf:"bytes,3,opt,name=reason_code,json=reasonCode,proto3" json:"reason_code,omitempty"`
+	Action        string                 `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"`
+	Conditions    []*ReasonCondition     `protobuf:"bytes,5,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	AlertType     string                 `protobuf:"bytes,6,opt,name=alert_type,json=alertType,proto3" json:"alert_type,omitempty"` // fraud | aml
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuleHit) Reset() {
+	*x = RuleHit{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuleHit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuleHit) ProtoMessage() {}
+
+func (x *RuleHit) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuleHit.ProtoReflect.Descriptor instead.
+func (*RuleHit) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RuleHit) GetRuleId() string {
+	if x != nil {
+		return x.RuleId
+	}
+	return ""
+}
+
+func (x *RuleHit) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *RuleHit) GetReasonCode() string {
+	if x != nil {
+		return x.ReasonCode
+	}
+	return ""
+}
+
+func (x *RuleHit) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *RuleHit) GetConditions() []*ReasonCondition {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+func (x *RuleHit) GetAlertType() string {
+	if x != nil {
+		return x.AlertType
+	}
+	return ""
+}
+
+// A reason behind a decision and how much it raised the risk
+type Reason struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Source        string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`                     // rule | signal | model
+	SourceId      string                 `protobuf:"bytes,3,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"` // rule id, signal id or model version
+	Contribution  float64                `protobuf:"fixed64,4,opt,name=contribution,proto3" json:"contribution,omitempty"`       // log-odds added to the risk score
+	Conditions    []*ReasonCondition     `protobuf:"bytes,5,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Reason) Reset() {
+	*x = Reason{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Reason) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reason) ProtoMessage() {}
+
+func (x *Reason) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reason.ProtoReflect.Descriptor instead.
+func (*Reason) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Reason) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Reason) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Reason) GetSourceId() string {
+	if x != nil {
+		return x.SourceId
+	}
+	return ""
+}
+
+func (x *Reason) GetContribution() float64 {
+	if x != nil {
+		return x.Contribution
+	}
+	return 0
+}
+
+func (x *Reason) GetConditions() []*ReasonCondition {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+// Published by the enricher to the enriched_transactions topic.
+// The encoding (protobuf, JSON or Avro) is advertised in the content-type header.
+type EnrichedTransaction struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Transaction    *TransactionRequest    `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	Geo            *GeoData               `protobuf:"bytes,2,opt,name=geo,proto3" json:"geo,omitempty"`
+	IpSignals      *FraudSignals          `protobuf:"bytes,3,opt,name=ip_signals,json=ipSignals,proto3" json:"ip_signals,omitempty"`
+	RuleSetVersion string                 `protobuf:"bytes,4,opt,name=rule_set_version,json=ruleSetVersion,proto3" json:"rule_set_version,omitempty"`
+	RuleHits       []*RuleHit             `protobuf:"bytes,5,rep,name=rule_hits,json=ruleHits,proto3" json:"rule_hits,omitempty"`
+	ShadowRuleHits []*RuleHit             `protobuf:"bytes,6,rep,name=shadow_rule_hits,json=shadowRuleHits,proto3" json:"shadow_rule_hits,omitempty"`
+	// public, private, loopback, link_local, multicast, documentation, cgnat,
+	// reserved or unspecified
+	IpClass string `protobuf:"bytes,7,opt,name=ip_class,json=ipClass,proto3" json:"ip_class,omitempty"`
+	// IPv6 only: the IPV6_AGG_PREFIX network (e.g. 2001:db8:1:2::/64) and the
+	// fraud signals aggregated over every address in it
+	IpPrefix      string        `protobuf:"bytes,8,opt,name=ip_prefix,json=ipPrefix,proto3" json:"ip_prefix,omitempty"`
+	PrefixSignals *FraudSignals `protobuf:"bytes,9,opt,name=prefix_signals,json=prefixSignals,proto3" json:"prefix_signals,omitempty"`
+	// Windowed aggregates for the IPv4 /24 and the ASN the IP belongs to
+	SubnetSignals *AggregateSignals `protobuf:"bytes,10,opt,name=subnet_signals,json=subnetSignals,proto3" json:"subnet_signals,omitempty"`
+	AsnSignals    *AggregateSignals `protobuf:"bytes,11,opt,name=asn_signals,json=asnSignals,proto3" json:"asn_signals,omitempty"`
+	// OK, NOT_FOUND, NON_ROUTABLE, UNAVAILABLE (geo provider down, geo fields
+	// empty) or ERROR
+	GeoStatus string            `protobuf:"bytes,12,opt,name=geo_status,json=geoStatus,proto3" json:"geo_status,omitempty"`
+	Anonymity *AnonymitySignals `protobuf:"bytes,13,opt,name=anonymity,proto3" json:"anonymity,omitempty"`
+	Balance   *BalanceSignals   `protobuf:"bytes,14,opt,name=balance,proto3" json:"balance,omitempty"`
+	// Model probability of fraud (0..1) and the version of the model that
+	// produced it; model_version is empty when no model is loaded
+	FraudScore   float64 `protobuf:"fixed64,15,opt,name=fraud_score,json=fraudScore,proto3" json:"fraud_score,omitempty"`
+	ModelVersion string  `protobuf:"bytes,16,opt,name=model_version,json=modelVersion,proto3" json:"model_version,omitempty"`
+	// Shadow scores of the challenger models, keyed by model version. Recorded
+	// for offline comparison only, never acted on.
+	ChallengerScores map[string]float64 `protobuf:"bytes,17,rep,name=challenger_scores,json=challengerScores,proto3" json:"challenger_scores,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	UserSignals      *UserSignals       `protobuf:"bytes,18,opt,name=user_signals,json=userSignals,proto3" json:"user_signals,omitempty"`
+	// Champion model explanation on the log-odds scale: model_base_value plus
+	// every contribution adds up to logit(fraud_score). Keyed by enriched
+	// feature path; path attribution (Saabas) for tree models, weight * value
+	// for logistic models.
+	ModelContributions map[string]float64 `protobuf:"bytes,19,rep,name=model_contributions,json=modelContributions,proto3" json:"model_contributions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	ModelBaseValue     float64            `protobuf:"fixed64,20,opt,name=model_base_value,json=modelBaseValue,proto3" json:"model_base_value,omitempty"`
+	// The decision published to fraud_decisions for this transaction
+	Decision *Decision `protobuf:"bytes,21,opt,name=decision,proto3" json:"decision,omitempty"`
+	// Event time the windowed signals were computed at (transaction timestamp,
+	// or the Kafka message timestamp if it had none) and the watermark of its
+	// source partition when it was processed, both unix millis
+	EventTime int64 `protobuf:"varint,22,opt,name=event_time,json=eventTime,proto3" json:"event_time,omitempty"`
+	Watermark int64 `protobuf:"varint,23,opt,name=watermark,proto3" json:"watermark,omitempty"`
+	// Card testing per IP, per subnet (the IPv4 /24 or the IPv6 aggregation
+	// prefix) and per user
+	IpCardTesting     *CardTestingSignals `protobuf:"bytes,24,opt,name=ip_card_testing,json=ipCardTesting,proto3" json:"ip_card_testing,omitempty"`
+	SubnetCardTesting *CardTestingSignals `protobuf:"bytes,25,opt,name=subnet_card_testing,json=subnetCardTesting,proto3" json:"subnet_card_testing,omitempty"`
+	UserCardTesting   *CardTestingSignals `protobuf:"bytes,26,opt,name=user_card_testing,json=userCardTesting,proto3" json:"user_card_testing,omitempty"`
+	// Structuring per user and per destination account (money laundering
+	// signals, alerted as alert_type aml)
+	UserStructuring *StructuringSignals `protobuf:"bytes,27,opt,name=user_structuring,json=userStructuring,proto3" json:"user_structuring,omitempty"`
+	DestStructuring *StructuringSignals `protobuf:"bytes,28,opt,name=dest_structuring,json=destStructuring,proto3" json:"dest_structuring,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *EnrichedTransaction) Reset() {
+	*x = EnrichedTransaction{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnrichedTransaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnrichedTransaction) ProtoMessage() {}
+
+func (x *EnrichedTransaction) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnrichedTransaction.ProtoReflect.Descriptor instead.
+func (*EnrichedTransaction) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *EnrichedTransaction) GetTransaction() *TransactionRequest {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetGeo() *GeoData {
+	if x != nil {
+		return x.Geo
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetIpSignals() *FraudSignals {
+	if x != nil {
+		return x.IpSignals
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetRuleSetVersion() string {
+	if x != nil {
+		return x.RuleSetVersion
+	}
+	return ""
+}
+
+func (x *EnrichedTransaction) GetRuleHits() []*RuleHit {
+	if x != nil {
+		return x.RuleHits
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetShadowRuleHits() []*RuleHit {
+	if x != nil {
+		return x.ShadowRuleHits
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetIpClass() string {
+	if x != nil {
+		return x.IpClass
+	}
+	return ""
+}
+
+func (x *EnrichedTransaction) GetIpPrefix() string {
+	if x != nil {
+		return x.IpPrefix
+	}
+	return ""
+}
+
+func (x *EnrichedTransaction) GetPrefixSignals() *FraudSignals {
+	if x != nil {
+		return x.PrefixSignals
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetSubnetSignals() *AggregateSignals {
+	if x != nil {
+		return x.SubnetSignals
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetAsnSignals() *AggregateSignals {
+	if x != nil {
+		return x.AsnSignals
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetGeoStatus() string {
+	if x != nil {
+		return x.GeoStatus
+	}
+	return ""
+}
+
+func (x *EnrichedTransaction) GetAnonymity() *AnonymitySignals {
+	if x != nil {
+		return x.Anonymity
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetBalance() *BalanceSignals {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetFraudScore() float64 {
+	if x != nil {
+		return x.FraudScore
+	}
+	return 0
+}
+
+func (x *EnrichedTransaction) GetModelVersion() string {
+	if x != nil {
+		return x.ModelVersion
+	}
+	return ""
+}
+
+func (x *EnrichedTransaction) GetChallengerScores() map[string]float64 {
+	if x != nil {
+		return x.ChallengerScores
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetUserSignals() *UserSignals {
+	if x != nil {
+		return x.UserSignals
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetModelContributions() map[string]float64 {
+	if x != nil {
+		return x.ModelContributions
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetModelBaseValue() float64 {
+	if x != nil {
+		return x.ModelBaseValue
+	}
+	return 0
+}
+
+func (x *EnrichedTransaction) GetDecision() *Decision {
+	if x != nil {
+		return x.Decision
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetEventTime() int64 {
+	if x != nil {
+		return x.EventTime
+	}
+	return 0
+}
+
+func (x *EnrichedTransaction) GetWatermark() int64 {
+	if x != nil {
+		return x.Watermark
+	}
+	return 0
+}
+
+func (x *EnrichedTransaction) GetIpCardTesting() *CardTestingSignals {
+	if x != nil {
+		return x.IpCardTesting
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetSubnetCardTesting() *CardTestingSignals {
+	if x != nil {
+		return x.SubnetCardTesting
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetUserCardTesting() *CardTestingSignals {
+	if x != nil {
+		return x.UserCardTesting
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetUserStructuring() *StructuringSignals {
+	if x != nil {
+		return x.UserStructuring
+	}
+	return nil
+}
+
+func (x *EnrichedTransaction) GetDestStructuring() *StructuringSignals {
+	if x != nil {
+		return x.DestStructuring
+	}
+	return nil
+}
+
+// Verdict of the decision stage for one enriched transaction. Published to
+// the fraud_decisions topic, keyed by user ID.
+type Decision struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	DecisionId     string                 `protobuf:"bytes,1,opt,name=decision_id,json=decisionId,proto3" json:"decision_id,omitempty"`
+	TransactionId  string                 `protobuf:"bytes,2,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	UserId         string                 `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	IpAddress      string                 `protobuf:"bytes,4,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	Outcome        string                 `protobuf:"bytes,5,opt,name=outcome,proto3" json:"outcome,omitempty"`                            // ALLOW | REVIEW | BLOCK
+	RiskScore      float64                `protobuf:"fixed64,6,opt,name=risk_score,json=riskScore,proto3" json:"risk_score,omitempty"`     // calibrated probability of fraud, 0..1
+	ReasonCodes    []string               `protobuf:"bytes,7,rep,name=reason_codes,json=reasonCodes,proto3" json:"reason_codes,omitempty"` // what raised the risk, largest contribution first
+	PolicyVersion  string                 `protobuf:"bytes,8,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"`
+	RuleSetVersion string                 `protobuf:"bytes,9,opt,name=rule_set_version,json=ruleSetVersion,proto3" json:"rule_set_version,omitempty"`
+	ModelVersion   string                 `protobuf:"bytes,10,opt,name=model_version,json=modelVersion,proto3" json:"model_version,omitempty"` // empty if the transaction was unscored
+	CreatedAt      int64                  `protobuf:"varint,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Reasons        []*Reason              `protobuf:"bytes,12,rep,name=reasons,proto3" json:"reasons,omitempty"` // same order as reason_codes
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Decision) Reset() {
+	*x = Decision{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Decision) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Decision) ProtoMessage() {}
+
+func (x *Decision) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Decision.ProtoReflect.Descriptor instead.
+func (*Decision) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Decision) GetDecisionId() string {
+	if x != nil {
+		return x.DecisionId
+	}
+	return ""
+}
+
+func (x *Decision) GetTransactionId() string {
+	if x != nil {
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *Decision) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Decision) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *Decision) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+func (x *Decision) GetRiskScore() float64 {
+	if x != nil {
+		return x.RiskScore
+	}
+	return 0
+}
+
+func (x *Decision) GetReasonCodes() []string {
+	if x != nil {
+		return x.ReasonCodes
+	}
+	return nil
+}
+
+func (x *Decision) GetPolicyVersion() string {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return ""
+}
+
+func (x *Decision) GetRuleSetVersion() string {
+	if x != nil {
+		return x.RuleSetVersion
+	}
+	return ""
+}
+
+func (x *Decision) GetModelVersion() string {
+	if x != nil {
+		return x.ModelVersion
+	}
+	return ""
+}
+
+func (x *Decision) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Decision) GetReasons() []*Reason {
+	if x != nil {
+		return x.Reasons
+	}
+	return nil
+}
+
+// Raised by the enricher when an active rule matches a transaction.
+// Fraud alerts are published to the fraud_alerts topic, keyed by IP address;
+// AML alerts to the aml_alerts topic, keyed by user ID.
+type Alert struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	AlertId        string                 `protobuf:"bytes,1,opt,name=alert_id,json=alertId,proto3" json:"alert_id,omitempty"`
+	TransactionId  string                 `protobuf:"bytes,2,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	UserId         string                 `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	IpAddress      string                 `protobuf:"bytes,4,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	RuleId         string                 `protobuf:"bytes,5,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	Severity       string                 `protobuf:"bytes,6,opt,name=severity,proto3" json:"severity,omitempty"`
+	ReasonCodes    []string               `protobuf:"bytes,7,rep,name=reason_codes,json=reasonCodes,proto3" json:"reason_codes,omitempty"`
+	Action         string                 `protobuf:"bytes,8,opt,name=action,proto3" json:"action,omitempty"`
+	RuleSetVersion string                 `protobuf:"bytes,9,opt,name=rule_set_version,json=ruleSetVersion,proto3" json:"rule_set_version,omitempty"`
+	CreatedAt      int64                  `protobuf:"varint,10,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// Snapshot of the enriched features the rule was evaluated against
+	NumericFeatures     map[string]float64 `protobuf:"bytes,11,rep,name=numeric_features,json=numericFeatures,proto3" json:"numeric_features,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`
+	CategoricalFeatures map[string]string  `protobuf:"bytes,12,rep,name=categorical_features,json=categoricalFeatures,proto3" json:"categorical_features,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// What made the rule match
+	Conditions    []*ReasonCondition `protobuf:"bytes,13,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	AlertType     string             `protobuf:"bytes,14,opt,name=alert_type,json=alertType,proto3" json:"alert_type,omitempty"` // fraud | aml
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Alert) Reset() {
+	*x = Alert{}
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Alert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Alert) ProtoMessage() {}
+
+func (x *Alert) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_fraud_v1_fraud_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Alert.ProtoReflect.Descriptor instead.
+func (*Alert) Descriptor() ([]byte, []int) {
+	return file_proto_fraud_v1_fraud_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Alert) GetAlertId() string {
+	if x != nil {
+		return x.AlertId
+	}
+	return ""
+}
+
+func (x *Alert) GetTransactionId() string {
+	if x != nil {
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *Alert) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Alert) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *Alert) GetRuleId() string {
+	if x != nil {
+		return x.RuleId
+	}
+	return ""
+}
+
+func (x *Alert) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Alert) GetReasonCodes() []string {
+	if x != nil {
+		return x.ReasonCodes
+	}
+	return nil
+}
+
+func (x *Alert) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Alert) GetRuleSetVersion() string {
+	if x != nil {
+		return x.RuleSetVersion
+	}
+	return ""
+}
+
+func (x *Alert) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Alert) GetNumericFeatures() map[string]float64 {
+	if x != nil {
+		return x.NumericFeatures
+	}
+	return nil
+}
+
+func (x *Alert) GetCategoricalFeatures() map[string]string {
+	if x != nil {
+		return x.CategoricalFeatures
+	}
+	return nil
+}
+
+func (x *Alert) GetConditions() []*ReasonCondition {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+func (x *Alert) GetAlertType() string {
+	if x != nil {
+		return x.AlertType
+	}
+	return ""
+}
+
+var File_proto_fraud_v1_fraud_proto protoreflect.FileDescriptor
+
+const file_proto_fraud_v1_fraud_proto_rawDesc = "" +
+	"\n" +
+	"\x1aproto/fraud/v1/fraud.proto\x12\x05fraud\"\xdf\x03\n" +
+	"\x12TransactionRequest\x12%\n" +
+	"\x0etransaction_id\x18\x01 \x01(\tR\rtransactionId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\x01R\x06amount\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12\x19\n" +
+	"\bis_fraud\x18\x05 \x01(\bR\aisFraud\x12\x12\n" +
+	"\x04type\x18\x06 \x01(\tR\x04type\x12(\n" +
+	"\x10old_balance_orig\x18\a \x01(\x01R\x0eoldBalanceOrig\x12(\n" +
+	"\x10new_balance_orig\x18\b \x01(\x01R\x0enewBalanceOrig\x12(\n" +
+	"\x10old_balance_dest\x18\t \x01(\x01R\x0eoldBalanceDest\x12(\n" +
+	"\x10new_balance_dest\x18\n" +
+	" \x01(\x01R\x0enewBalanceDest\x12:\n" +
+	"\x19is_unauthorized_overdraft\x18\v \x01(\x01R\x17isUnauthorizedOverdraft\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\f \x01(\tR\tipAddress\x12!\n" +
+	"\fdest_account\x18\r \x01(\tR\vdestAccount\"G\n" +
+	"\x11IngestionResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x94\x02\n" +
+	"\aGeoData\x12\x12\n" +
+	"\x04city\x18\x01 \x01(\tR\x04city\x12\x18\n" +
+	"\acountry\x18\x02 \x01(\tR\acountry\x12!\n" +
+	"\fcountry_code\x18\x03 \x01(\tR\vcountryCode\x12\x1a\n" +
+	"\blatitude\x18\x04 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x05 \x01(\x01R\tlongitude\x12\x10\n" +
+	"\x03asn\x18\x06 \x01(\tR\x03asn\x12\x10\n" +
+	"\x03isp\x18\a \x01(\tR\x03isp\x12\x1d\n" +
+	"\n" +
+	"is_hosting\x18\b \x01(\bR\tisHosting\x12\x18\n" +
+	"\anetwork\x18\t \x01(\tR\anetwork\x12!\n" +
+	"\fnetwork_type\x18\n" +
+	" \x01(\tR\vnetworkType\"\xf1\x01\n" +
+	"\fFraudSignals\x12\x1d\n" +
+	"\n" +
+	"first_seen\x18\x01 \x01(\x03R\tfirstSeen\x12\x1b\n" +
+	"\tlast_seen\x18\x02 \x01(\x03R\blastSeen\x12\x1b\n" +
+	"\ttxn_count\x18\x03 \x01(\x03R\btxnCount\x12!\n" +
+	"\ftotal_amount\x18\x04 \x01(\x01R\vtotalAmount\x12'\n" +
+	"\x0famount_velocity\x18\x05 \x01(\x01R\x0eamountVelocity\x12\x1d\n" +
+	"\n" +
+	"avg_amount\x18\x06 \x01(\x01R\tavgAmount\x12\x1d\n" +
+	"\n" +
+	"max_amount\x18\a \x01(\x01R\tmaxAmount\"\xbd\x01\n" +
+	"\x10AnonymitySignals\x12!\n" +
+	"\fis_anonymous\x18\x01 \x01(\bR\visAnonymous\x12\x15\n" +
+	"\x06is_tor\x18\x02 \x01(\bR\x05isTor\x12\x15\n" +
+	"\x06is_vpn\x18\x03 \x01(\bR\x05isVpn\x12&\n" +
+	"\x0fis_public_proxy\x18\x04 \x01(\bR\risPublicProxy\x120\n" +
+	"\x14is_residential_proxy\x18\x05 \x01(\bR\x12isResidentialProxy\"\xec\x01\n" +
+	"\x0eBalanceSignals\x12,\n" +
+	"\x12error_balance_orig\x18\x01 \x01(\x01R\x10errorBalanceOrig\x12,\n" +
+	"\x12error_balance_dest\x18\x02 \x01(\x01R\x10errorBalanceDest\x12!\n" +
+	"\forig_drained\x18\x03 \x01(\bR\vorigDrained\x12%\n" +
+	"\x0edest_unchanged\x18\x04 \x01(\bR\rdestUnchanged\x124\n" +
+	"\x16amount_exceeds_balance\x18\x05 \x01(\bR\x14amountExceedsBalance\"\xb2\x01\n" +
+	"\x10AggregateSignals\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1b\n" +
+	"\ttxn_count\x18\x02 \x01(\x03R\btxnCount\x12%\n" +
+	"\x0edistinct_users\x18\x03 \x01(\x03R\rdistinctUsers\x12!\n" +
+	"\ftotal_amount\x18\x04 \x01(\x01R\vtotalAmount\x12%\n" +
+	"\x0ewindow_seconds\x18\x05 \x01(\x03R\rwindowSeconds\"U\n" +
+	"\vUserSignals\x12\x1f\n" +
+	"\vnew_country\x18\x01 \x01(\bR\n" +
+	"newCountry\x12%\n" +
+	"\x0ecountries_seen\x18\x02 \x01(\x03R\rcountriesSeen\"\x96\x02\n" +
+	"\x12CardTestingSignals\x12&\n" +
+	"\x0fsmall_txn_count\x18\x01 \x01(\x03R\rsmallTxnCount\x12&\n" +
+	"\x0fsmall_txn_total\x18\x02 \x01(\x01R\rsmallTxnTotal\x12(\n" +
+	"\x10max_small_amount\x18\x03 \x01(\x01R\x0emaxSmallAmount\x12\x14\n" +
+	"\x05burst\x18\x04 \x01(\bR\x05burst\x12\x1e\n" +
+	"\n" +
+	"escalation\x18\x05 \x01(\bR\n" +
+	"escalation\x12)\n" +
+	"\x10escalation_ratio\x18\x06 \x01(\x01R\x0fescalationRatio\x12%\n" +
+	"\x0ewindow_seconds\x18\a \x01(\x03R\rwindowSeconds\"\xe0\x01\n" +
+	"\x12StructuringSignals\x12\x1c\n" +
+	"\tthreshold\x18\x01 \x01(\x01R\tthreshold\x12$\n" +
+	"\x0eband_txn_count\x18\x02 \x01(\x03R\fbandTxnCount\x12$\n" +
+	"\x0eband_txn_total\x18\x03 \x01(\x01R\fbandTxnTotal\x12\x17\n" +
+	"\ain_band\x18\x04 \x01(\bR\x06inBand\x12 \n" +
+	"\vstructuring\x18\x05 \x01(\bR\vstructuring\x12%\n" +
+	"\x0ewindow_seconds\x18\x06 \x01(\x03R\rwindowSeconds\"\xc1\x01\n" +
+	"\x0fReasonCondition\x12\x18\n" +
+	"\afeature\x18\x01 \x01(\tR\afeature\x12\x1a\n" +
+	"\boperator\x18\x02 \x01(\tR\boperator\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x01R\x05value\x12\x1c\n" +
+	"\tthreshold\x18\x04 \x01(\x01R\tthreshold\x12\x1d\n" +
+	"\n" +
+	"value_text\x18\x05 \x01(\tR\tvalueText\x12%\n" +
+	"\x0ethreshold_text\x18\x06 \x01(\tR\rthresholdText\"\xce\x01\n" +
+	"\aRuleHit\x12\x17\n" +
+	"\arule_id\x18\x01 \x01(\tR\x06ruleId\x12\x1a\n" +
+	"\bseverity\x18\x02 \x01(\tR\bseverity\x12\x1f\n" +
+	"\vreason_code\x18\x03 \x01(\tR\n" +
+	"reasonCode\x12\x16\n" +
+	"\x06action\x18\x04 \x01(\tR\x06action\x126\n" +
+	"\n" +
+	"conditions\x18\x05 \x03(\v2\x16.fraud.ReasonConditionR\n" +
+	"conditions\x12\x1d\n" +
+	"\n" +
+	"alert_type\x18\x06 \x01(\tR\talertType\"\xad\x01\n" +
+	"\x06Reason\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12\x1b\n" +
+	"\tsource_id\x18\x03 \x01(\tR\bsourceId\x12\"\n" +
+	"\fcontribution\x18\x04 \x01(\x01R\fcontribution\x126\n" +
+	"\n" +
+	"conditions\x18\x05 \x03(\v2\x16.fraud.ReasonConditionR\n" +
+	"conditions\"\xf0\f\n" +
+	"\x13EnrichedTransaction\x12;\n" +
+	"\vtransaction\x18\x01 \x01(\v2\x19.fraud.TransactionRequestR\vtransaction\x12 \n" +
+	"\x03geo\x18\x02 \x01(\v2\x0e.fraud.GeoDataR\x03geo\x122\n" +
+	"\n" +
+	"ip_signals\x18\x03 \x01(\v2\x13.fraud.FraudSignalsR\tipSignals\x12(\n" +
+	"\x10rule_set_version\x18\x04 \x01(\tR\x0eruleSetVersion\x12+\n" +
+	"\trule_hits\x18\x05 \x03(\v2\x0e.fraud.RuleHitR\bruleHits\x128\n" +
+	"\x10shadow_rule_hits\x18\x06 \x03(\v2\x0e.fraud.RuleHitR\x0eshadowRuleHits\x12\x19\n" +
+	"\bip_class\x18\a \x01(\tR\aipClass\x12\x1b\n" +
+	"\tip_prefix\x18\b \x01(\tR\bipPrefix\x12:\n" +
+	"\x0eprefix_signals\x18\t \x01(\v2\x13.fraud.FraudSignalsR\rprefixSignals\x12>\n" +
+	"\x0esubnet_signals\x18\n" +
+	" \x01(\v2\x17.fraud.AggregateSignalsR\rsubnetSignals\x128\n" +
+	"\vasn_signals\x18\v \x01(\v2\x17.fraud.AggregateSignalsR\n" +
+	"asnSignals\x12\x1d\n" +
+	"\n" +
+	"geo_status\x18\f \x01(\tR\tgeoStatus\x125\n" +
+	"\tanonymity\x18\r \x01(\v2\x17.fraud.AnonymitySignalsR\tanonymity\x12/\n" +
+	"\abalance\x18\x0e \x01(\v2\x15.fraud.BalanceSignalsR\abalance\x12\x1f\n" +
+	"\vfraud_score\x18\x0f \x01(\x01R\n" +
+	"fraudScore\x12#\n" +
+	"\rmodel_version\x18\x10 \x01(\tR\fmodelVersion\x12]\n" +
+	"\x11challenger_scores\x18\x11 \x03(\v20.fraud.EnrichedTransaction.ChallengerScoresEntryR\x10challengerScores\x125\n" +
+	"\fuser_signals\x18\x12 \x01(\v2\x12.fraud.UserSignalsR\vuserSignals\x12c\n" +
+	"\x13model_contributions\x18\x13 \x03(\v22.fraud.EnrichedTransaction.ModelContributionsEntryR\x12modelContributions\x12(\n" +
+	"\x10model_base_value\x18\x14 \x01(\x01R\x0emodelBaseValue\x12+\n" +
+	"\bdecision\x18\x15 \x01(\v2\x0f.fraud.DecisionR\bdecision\x12\x1d\n" +
+	"\n" +
+	"event_time\x18\x16 \x01(\x03R\teventTime\x12\x1c\n" +
+	"\twatermark\x18\x17 \x01(\x03R\twatermark\x12A\n" +
+	"\x0fip_card_testing\x18\x18 \x01(\v2\x19.fraud.CardTestingSignalsR\ripCardTesting\x12I\n" +
+	"\x13subnet_card_testing\x18\x19 \x01(\v2\x19.fraud.CardTestingSignalsR\x11subnetCardTesting\x12E\n" +
+	"\x11user_card_testing\x18\x1a \x01(\v2\x19.fraud.CardTestingSignalsR\x0fuserCardTesting\x12D\n" +
+	"\x10user_structuring\x18\x1b \x01(\v2\x19.fraud.StructuringSignalsR\x0fuserStructuring\x12D\n" +
+	"\x10dest_structuring\x18\x1c \x01(\v2\x19.fraud.StructuringSignalsR\x0fdestStructuring\x1aC\n" +
+	"\x15ChallengerScoresEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\x1aE\n" +
+	"\x17ModelContributionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"\xa4\x03\n" +
+	"\bDecision\x12\x1f\n" +
+	"\vdecision_id\x18\x01 \x01(\tR\n" +
+	"decisionId\x12%\n" +
+	"\x0etransaction_id\x18\x02 \x01(\tR\rtransactionId\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x04 \x01(\tR\tipAddress\x12\x18\n" +
+	"\aoutcome\x18\x05 \x01(\tR\aoutcome\x12\x1d\n" +
+	"\n" +
+	"risk_score\x18\x06 \x01(\x01R\triskScore\x12!\n" +
+	"\freason_codes\x18\a \x03(\tR\vreasonCodes\x12%\n" +
+	"\x0epolicy_version\x18\b \x01(\tR\rpolicyVersion\x12(\n" +
+	"\x10rule_set_version\x18\t \x01(\tR\x0eruleSetVersion\x12#\n" +
+	"\rmodel_version\x18\n" +
+	" \x01(\tR\fmodelVersion\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\v \x01(\x03R\tcreatedAt\x12'\n" +
+	"\areasons\x18\f \x03(\v2\r.fraud.ReasonR\areasons\"\xc5\x05\n" +
+	"\x05Alert\x12\x19\n" +
+	"\balert_id\x18\x01 \x01(\tR\aalertId\x12%\n" +
+	"\x0etransaction_id\x18\x02 \x01(\tR\rtransactionId\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x04 \x01(\tR\tipAddress\x12\x17\n" +
+	"\arule_id\x18\x05 \x01(\tR\x06ruleId\x12\x1a\n" +
+	"\bseverity\x18\x06 \x01(\tR\bseverity\x12!\n" +
+	"\freason_codes\x18\a \x03(\tR\vreasonCodes\x12\x16\n" +
+	"\x06action\x18\b \x01(\tR\x06action\x12(\n" +
+	"\x10rule_set_version\x18\t \x01(\tR\x0eruleSetVersion\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\n" +
+	" \x01(\x03R\tcreatedAt\x12L\n" +
+	"\x10numeric_features\x18\v \x03(\v2!.fraud.Alert.NumericFeaturesEntryR\x0fnumericFeatures\x12X\n" +
+	"\x14categorical_features\x18\f \x03(\v2%.fraud.Alert.CategoricalFeaturesEntryR\x13categoricalFeatures\x126\n" +
+	"\n" +
+	"conditions\x18\r \x03(\v2\x16.fraud.ReasonConditionR\n" +
+	"conditions\x12\x1d\n" +
+	"\n" +
+	"alert_type\x18\x0e \x01(\tR\talertType\x1aB\n" +
+	"\x14NumericFeaturesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\x1aF\n" +
+	"\x18CategoricalFeaturesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012X\n" +
 	"\x0eFraudIngestion\x12F\n" +
 	"\x0fSendTransaction\x12\x19.fraud.TransactionRequest\x1a\x18.fraud.IngestionResponseB\x06Z\x04./pbb\x06proto3"
 
@@ -243,19 +1929,62 @@ func file_proto_fraud_v1_fraud_proto_rawDescGZIP() []byte {
 	return file_proto_fraud_v1_fraud_proto_rawDescData
 }
 
-var file_proto_fraud_v1_fraud_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_fraud_v1_fraud_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
 var file_proto_fraud_v1_fraud_proto_goTypes = []any{
-	(*TransactionRequest)(nil), // 0: fraud.TransactionRequest
-	(*IngestionResponse)(nil),  // 1: fraud.IngestionResponse
+	(*TransactionRequest)(nil),  // 0: fraud.TransactionRequest
+	(*IngestionResponse)(nil),   // 1: fraud.IngestionResponse
+	(*GeoData)(nil),             // 2: fraud.GeoData
+	(*FraudSignals)(nil),        // 3: fraud.FraudSignals
+	(*AnonymitySignals)(nil),    // 4: fraud.AnonymitySignals
+	(*BalanceSignals)(nil),      // 5: fraud.BalanceSignals
+	(*AggregateSignals)(nil),    // 6: fraud.AggregateSignals
+	(*UserSignals)(nil),         // 7: fraud.UserSignals
+	(*CardTestingSignals)(nil),  // 8: fraud.CardTestingSignals
+	(*StructuringSignals)(nil),  // 9: fraud.StructuringSignals
+	(*ReasonCondition)(nil),     // 10: fraud.ReasonCondition
+	(*RuleHit)(nil),             // 11: fraud.RuleHit
+	(*Reason)(nil),              // 12: fraud.Reason
+	(*EnrichedTransaction)(nil), // 13: fraud.EnrichedTransaction
+	(*Decision)(nil),            // 14: fraud.Decision
+	(*Alert)(nil),               // 15: fraud.Alert
+	nil,                         // 16: fraud.EnrichedTransaction.ChallengerScoresEntry
+	nil,                         // 17: fraud.EnrichedTransaction.ModelContributionsEntry
+	nil,                         // 18: fraud.Alert.NumericFeaturesEntry
+	nil,                         // 19: fraud.Alert.CategoricalFeaturesEntry
 }
 var file_proto_fraud_v1_fraud_proto_depIdxs = []int32{
-	0, // 0: fraud.FraudIngestion.SendTransaction:input_type -> fraud.TransactionRequest
-	1, // 1: fraud.FraudIngestion.SendTransaction:output_type -> fraud.IngestionResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	10, // 0: fraud.RuleHit.conditions:type_name -> fraud.ReasonCondition
+	10, // 1: fraud.Reason.conditions:type_name -> fraud.ReasonCondition
+	0,  // 2: fraud.EnrichedTransaction.transaction:type_name -> fraud.TransactionRequest
+	2,  // 3: fraud.EnrichedTransaction.geo:type_name -> fraud.GeoData
+	3,  // 4: fraud.EnrichedTransaction.ip_signals:type_name -> fraud.FraudSignals
+	11, // 5: fraud.EnrichedTransaction.rule_hits:type_name -> fraud.RuleHit
+	11, // 6: fraud.EnrichedTransaction.shadow_rule_hits:type_name -> fraud.RuleHit
+	3,  // 7: fraud.EnrichedTransaction.prefix_signals:type_name -> fraud.FraudSignals
+	6,  // 8: fraud.EnrichedTransaction.subnet_signals:type_name -> fraud.AggregateSignals
+	6,  // 9: fraud.EnrichedTransaction.asn_signals:type_name -> fraud.AggregateSignals
+	4,  // 10: fraud.EnrichedTransaction.anonymity:type_name -> fraud.AnonymitySignals
+	5,  // 11: fraud.EnrichedTransaction.balance:type_name -> fraud.BalanceSignals
+	16, // 12: fraud.EnrichedTransaction.challenger_scores:type_name -> fraud.EnrichedTransaction.ChallengerScoresEntry
+	7,  // 13: fraud.EnrichedTransaction.user_signals:type_name -> fraud.UserSignals
+	17, // 14: fraud.EnrichedTransaction.model_contributions:type_name -> fraud.EnrichedTransaction.ModelContributionsEntry
+	14, // 15: fraud.EnrichedTransaction.decision:type_name -> fraud.Decision
+	8,  // 16: fraud.EnrichedTransaction.ip_card_testing:type_name -> fraud.CardTestingSignals
+	8,  // 17: fraud.EnrichedTransaction.subnet_card_testing:type_name -> fraud.CardTestingSignals
+	8,  // 18: fraud.EnrichedTransaction.user_card_testing:type_name -> fraud.CardTestingSignals
+	9,  // 19: fraud.EnrichedTransaction.user_structuring:type_name -> fraud.StructuringSignals
+	9,  // 20: fraud.EnrichedTransaction.dest_structuring:type_name -> fraud.StructuringSignals
+	12, // 21: fraud.Decision.reasons:type_name -> fraud.Reason
+	18, // 22: fraud.Alert.numeric_features:type_name -> fraud.Alert.NumericFeaturesEntry
+	19, // 23: fraud.Alert.categorical_features:type_name -> fraud.Alert.CategoricalFeaturesEntry
+	10, // 24: fraud.Alert.conditions:type_name -> fraud.ReasonCondition
+	0,  // 25: fraud.FraudIngestion.SendTransaction:input_type -> fraud.TransactionRequest
+	1,  // 26: fraud.FraudIngestion.SendTransaction:output_type -> fraud.IngestionResponse
+	26, // [26:27] is the sub-list for method output_type
+	25, // [25:26] is the sub-list for method input_type
+	25, // [25:25] is the sub-list for extension type_name
+	25, // [25:25] is the sub-list for extension extendee
+	0,  // [0:25] is the sub-list for field type_name
 }
 
 func init() { file_proto_fraud_v1_fraud_proto_init() }
@@ -269,7 +1998,7 @@ func file_proto_fraud_v1_fraud_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_fraud_v1_fraud_proto_rawDesc), len(file_proto_fraud_v1_fraud_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   20,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -24,7 +24,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x1aproto/fraud/v1/fraud.proto\x12\x05\x66raud\"\x9f\x02\n\x12TransactionRequest\x12\x16\n\x0etransaction_id\x18\x01 \x01(\t\x12\x0f\n\x07user_id\x18\x02 \x01(\t\x12\x0e\n\x06\x61mount\x18\x03 \x01(\x01\x12\x11\n\ttimestamp\x18\x04 \x01(\x03\x12\x10\n\x08is_fraud\x18\x05 \x01(\x08\x12\x0c\n\x04type\x18\x06 \x01(\t\x12\x18\n\x10old_balance_orig\x18\x07 \x01(\x01\x12\x18\n\x10new_balance_orig\x18\x08 \x01(\x01\x12\x18\n\x10old_balance_dest\x18\t \x01(\x01\x12\x18\n\x10new_balance_dest\x18\n \x01(\x01\x12!\n\x19is_unauthorized_overdraft\x18\x0b \x01(\x01\x12\x12\n\nip_address\x18\x0c \x01(\t\"5\n\x11IngestionResponse\x12\x0f\n\x07success\x18\x01 \x01(\x08\x12\x0f\n\x07message\x18\x02 \x01(\t2X\n\x0e\x46raudIngestion\x12\x46\n\x0fSendTransaction\x12\x19.fraud.TransactionRequest\x1a\x18.fraud.IngestionResponseB\x06Z\x04./pbb\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x1aproto/fraud/v1/fraud.proto\x12\x05\x66raud\"\xb5\x02\n\x12TransactionRequest\x12\x16\n\x0etransaction_id\x18\x01 \x01(\t\x12\x0f\n\x07user_id\x18\x02 \x01(\t\x12\x0e\n\x06\x61mount\x18\x03 \x01(\x01\x12\x11\n\ttimestamp\x18\x04 \x01(\x03\x12\x10\n\x08is_fraud\x18\x05 \x01(\x08\x12\x0c\n\x04type\x18\x06 \x01(\t\x12\x18\n\x10old_balance_orig\x18\x07 \x01(\x01\x12\x18\n\x10new_balance_orig\x18\x08 \x01(\x01\x12\x18\n\x10old_balance_dest\x18\t \x01(\x01\x12\x18\n\x10new_balance_dest\x18\n \x01(\x01\x12!\n\x19is_unauthorized_overdraft\x18\x0b \x01(\x01\x12\x12\n\nip_address\x18\x0c \x01(\t\x12\x14\n\x0c\x64\x65st_account\x18\r \x01(\t\"5\n\x11IngestionResponse\x12\x0f\n\x07success\x18\x01 \x01(\x08\x12\x0f\n\x07message\x18\x02 \x01(\t\"\xb8\x01\n\x07GeoData\x12\x0c\n\x04\x63ity\x18\x01 \x01(\t\x12\x0f\n\x07\x63ountry\x18\x02 \x01(\t\x12\x14\n\x0c\x63ountry_code\x18\x03 \x01(\t\x12\x10\n\x08latitude\x18\x04 \x01(\x01\x12\x11\n\tlongitude\x18\x05 \x01(\x01\x12\x0b\n\x03\x61sn\x18\x06 \x01(\t\x12\x0b\n\x03isp\x18\x07 \x01(\t\x12\x12\n\nis_hosting\x18\x08 \x01(\x08\x12\x0f\n\x07network\x18\t \x01(\t\x12\x14\n\x0cnetwork_type\x18\n \x01(\t\"\x9f\x01\n\x0c\x46raudSignals\x12\x12\n\nfirst_seen\x18\x01 \x01(\x03\x12\x11\n\tlast_seen\x18\x02 \x01(\x03\x12\x11\n\ttxn_count\x18\x03 \x01(\x03\x12\x14\n\x0ctotal_amount\x18\x04 \x01(\x01\x12\x17\n\x0f\x61mount_velocity\x18\x05 \x01(\x01\x12\x12\n\navg_amount\x18\x06 \x01(\x01\x12\x12\n\nmax_amount\x18\x07 \x01(\x01\"\x7f\n\x10\x41nonymitySignals\x12\x14\n\x0cis_anonymous\x18\x01 \x01(\x08\x12\x0e\n\x06is_tor\x18\x02 \x01(\x08\x12\x0e\n\x06is_vpn\x18\x03 \x01(\x08\x12\x17\n\x0fis_public_proxy\x18\x04 \x01(\x08\x12\x1c\n\x14is_residential_proxy\x18\x05 \x01(\x08\"\x96\x01\n\x0e\x42\x61lanceSignals\x12\x1a\n\x12\x65rror_balance_orig\x18\x01 \x01(\x01\x12\x1a\n\x12\x65rror_balance_dest\x18\x02 \x01(\x01\x12\x14\n\x0corig_drained\x18\x03 \x01(\x08\x12\x16\n\x0e\x64\x65st_unchanged\x18\x04 \x01(\x08\x12\x1e\n\x16\x61mount_exceeds_balance\x18\x05 \x01(\x08\"x\n\x10\x41ggregateSignals\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x11\n\ttxn_count\x18\x02 \x01(\x03\x12\x16\n\x0e\x64istinct_users\x18\x03 \x01(\x03\x12\x14\n\x0ctotal_amount\x18\x04 \x01(\x01\x12\x16\n\x0ewindow_seconds\x18\x05 \x01(\x03\":\n\x0bUserSignals\x12\x13\n\x0bnew_country\x18\x01 \x01(\x08\x12\x16\n\x0e\x63ountries_seen\x18\x02 \x01(\x03\"\xb5\x01\n\x12\x43\x61rdTestingSignals\x12\x17\n\x0fsmall_txn_count\x18\x01 \x01(\x03\x12\x17\n\x0fsmall_txn_total\x18\x02 \x01(\x01\x12\x18\n\x10max_small_amount\x18\x03 \x01(\x01\x12\r\n\x05\x62urst\x18\x04 \x01(\x08\x12\x12\n\nescalation\x18\x05 \x01(\x08\x12\x18\n\x10\x65scalation_ratio\x18\x06 \x01(\x01\x12\x16\n\x0ewindow_seconds\x18\x07 \x01(\x03\"\x95\x01\n\x12StructuringSignals\x12\x11\n\tthreshold\x18\x01 \x01(\x01\x12\x16\n\x0e\x62\x61nd_txn_count\x18\x02 \x01(\x03\x12\x16\n\x0e\x62\x61nd_txn_total\x18\x03 \x01(\x01\x12\x0f\n\x07in_band\x18\x04 \x01(\x08\x12\x13\n\x0bstructuring\x18\x05 \x01(\x08\x12\x16\n\x0ewindow_seconds\x18\x06 \x01(\x03\"\x82\x01\n\x0fReasonCondition\x12\x0f\n\x07\x66\x65\x61ture\x18\x01 \x01(\t\x12\x10\n\x08operator\x18\x02 \x01(\t\x12\r\n\x05value\x18\x03 \x01(\x01\x12\x11\n\tthreshold\x18\x04 \x01(\x01\x12\x12\n\nvalue_text\x18\x05 \x01(\t\x12\x16\n\x0ethreshold_text\x18\x06 \x01(\t\"\x91\x01\n\x07RuleHit\x12\x0f\n\x07rule_id\x18\x01 \x01(\t\x12\x10\n\x08severity\x18\x02 \x01(\t\x12\x13\n\x0breason_code\x18\x03 \x01(\t\x12\x0e\n\x06\x61\x63tion\x18\x04 \x01(\t\x12*\n\nconditions\x18\x05 \x03(\x0b\x32\x16.fraud.ReasonCondition\x12\x12\n\nalert_type\x18\x06 \x01(\t\"{\n\x06Reason\x12\x0c\n\x04\x63ode\x18\x01 \x01(\t\x12\x0e\n\x06source\x18\x02 \x01(\t\x12\x11\n\tsource_id\x18\x03 \x01(\t\x12\x14\n\x0c\x63ontribution\x18\x04 \x01(\x01\x12*\n\nconditions\x18\x05 \x03(\x0b\x32\x16.fraud.ReasonCondition\"\xe3\t\n\x13\x45nrichedTransaction\x12.\n\x0btransaction\x18\x01 \x01(\x0b\x32\x19.fraud.TransactionRequest\x12\x1b\n\x03geo\x18\x02 \x01(\x0b\x32\x0e.fraud.GeoData\x12\'\n\nip_signals\x18\x03 \x01(\x0b\x32\x13.fraud.FraudSignals\x12\x18\n\x10rule_set_version\x18\x04 \x01(\t\x12!\n\trule_hits\x18\x05 \x03(\x0b\x32\x0e.fraud.RuleHit\x12(\n\x10shadow_rule_hits\x18\x06 \x03(\x0b\x32\x0e.fraud.RuleHit\x12\x10\n\x08ip_class\x18\x07 \x01(\t\x12\x11\n\tip_prefix\x18\x08 \x01(\t\x12+\n\x0eprefix_signals\x18\t \x01(\x0b\x32\x13.fraud.FraudSignals\x12/\n\x0esubnet_signals\x18\n \x01(\x0b\x32\x17.fraud.AggregateSignals\x12,\n\x0b\x61sn_signals\x18\x0b \x01(\x0b\x32\x17.fraud.AggregateSignals\x12\x12\n\ngeo_status\x18\x0c \x01(\t\x12*\n\tanonymity\x18\r \x01(\x0b\x32\x17.fraud.AnonymitySignals\x12&\n\x07\x62\x61lance\x18\x0e \x01(\x0b\x32\x15.fraud.BalanceSignals\x12\x13\n\x0b\x66raud_score\x18\x0f \x01(\x01\x12\x15\n\rmodel_version\x18\x10 \x01(\t\x12K\n\x11\x63hallenger_scores\x18\x11 \x03(\x0b\x32\x30.fraud.EnrichedTransaction.ChallengerScoresEntry\x12(\n\x0cuser_signals\x18\x12 \x01(\x0b\x32\x12.fraud.UserSignals\x12O\n\x13model_contributions\x18\x13 \x03(\x0b\x32\x32.fraud.EnrichedTransaction.ModelContributionsEntry\x12\x18\n\x10model_base_value\x18\x14 \x01(\x01\x12!\n\x08\x64\x65\x63ision\x18\x15 \x01(\x0b\x32\x0f.fraud.Decision\x12\x12\n\nevent_time\x18\x16 \x01(\x03\x12\x11\n\twatermark\x18\x17 \x01(\x03\x12\x32\n\x0fip_card_testing\x18\x18 \x01(\x0b\x32\x19.fraud.CardTestingSignals\x12\x36\n\x13subnet_card_testing\x18\x19 \x01(\x0b\x32\x19.fraud.CardTestingSignals\x12\x34\n\x11user_card_testing\x18\x1a \x01(\x0b\x32\x19.fraud.CardTestingSignals\x12\x33\n\x10user_structuring\x18\x1b \x01(\x0b\x32\x19.fraud.StructuringSignals\x12\x33\n\x10\x64\x65st_structuring\x18\x1c \x01(\x0b\x32\x19.fraud.StructuringSignals\x1a\x37\n\x15\x43hallengerScoresEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01:\x02\x38\x01\x1a\x39\n\x17ModelContributionsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01:\x02\x38\x01\"\x94\x02\n\x08\x44\x65\x63ision\x12\x13\n\x0b\x64\x65\x63ision_id\x18\x01 \x01(\t\x12\x16\n\x0etransaction_id\x18\x02 \x01(\t\x12\x0f\n\x07user_id\x18\x03 \x01(\t\x12\x12\n\nip_address\x18\x04 \x01(\t\x12\x0f\n\x07outcome\x18\x05 \x01(\t\x12\x12\n\nrisk_score\x18\x06 \x01(\x01\x12\x14\n\x0creason_codes\x18\x07 \x03(\t\x12\x16\n\x0epolicy_version\x18\x08 \x01(\t\x12\x18\n\x10rule_set_version\x18\t \x01(\t\x12\x15\n\rmodel_version\x18\n \x01(\t\x12\x12\n\ncreated_at\x18\x0b \x01(\x03\x12\x1e\n\x07reasons\x18\x0c \x03(\x0b\x32\r.fraud.Reason\"\x83\x04\n\x05\x41lert\x12\x10\n\x08\x61lert_id\x18\x01 \x01(\t\x12\x16\n\x0etransaction_id\x18\x02 \x01(\t\x12\x0f\n\x07user_id\x18\x03 \x01(\t\x12\x12\n\nip_address\x18\x04 \x01(\t\x12\x0f\n\x07rule_id\x18\x05 \x01(\t\x12\x10\n\x08severity\x18\x06 \x01(\t\x12\x14\n\x0creason_codes\x18\x07 \x03(\t\x12\x0e\n\x06\x61\x63tion\x18\x08 \x01(\t\x12\x18\n\x10rule_set_version\x18\t \x01(\t\x12\x12\n\ncreated_at\x18\n \x01(\x03\x12;\n\x10numeric_features\x18\x0b \x03(\x0b\x32!.fraud.Alert.NumericFeaturesEntry\x12\x43\n\x14\x63\x61tegorical_features\x18\x0c \x03(\x0b\x32%.fraud.Alert.CategoricalFeaturesEntry\x12*\n\nconditions\x18\r \x03(\x0b\x32\x16.fraud.ReasonCondition\x12\x12\n\nalert_type\x18\x0e \x01(\t\x1a\x36\n\x14NumericFeaturesEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01:\x02\x38\x01\x1a:\n\x18\x43\x61tegoricalFeaturesEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\x32X\n\x0e\x46raudIngestion\x12\x46\n\x0fSendTransaction\x12\x19.fraud.TransactionRequest\x1a\x18.fraud.IngestionResponseB\x06Z\x04./pbb\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -32,10 +32,54 @@ _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'proto.fraud.v1.fraud_pb2',
 if not _descriptor._USE_C_DESCRIPTORS:
   _globals['DESCRIPTOR']._loaded_options = None
   _globals['DESCRIPTOR']._serialized_options = b'Z\004./pb'
+  _globals['_ENRICHEDTRANSACTION_CHALLENGERSCORESENTRY']._loaded_options = None
+  _globals['_ENRICHEDTRANSACTION_CHALLENGERSCORESENTRY']._serialized_options = b'8\001'
+  _globals['_ENRICHEDTRANSACTION_MODELCONTRIBUTIONSENTRY']._loaded_options = None
+  _globals['_ENRICHEDTRANSACTION_MODELCONTRIBUTIONSENTRY']._serialized_options = b'8\001'
+  _globals['_ALERT_NUMERICFEATURESENTRY']._loaded_options = None
+  _globals['_ALERT_NUMERICFEATURESENTRY']._serialized_options = b'8\001'
+  _globals['_ALERT_CATEGORICALFEATURESENTRY']._loaded_options = None
+  _globals['_ALERT_CATEGORICALFEATURESENTRY']._serialized_options = b'8\001'
   _globals['_TRANSACTIONREQUEST']._serialized_start=38
-  _globals['_TRANSACTIONREQUEST']._serialized_end=325
-  _globals['_INGESTIONRESPONSE']._serialized_start=327
-  _globals['_INGESTIONRESPONSE']._serialized_end=380
-  _globals['_FRAUDINGESTION']._serialized_start=382
-  _globals['_FRAUDINGESTION']._serialized_end=470
+  _globals['_TRANSACTIONREQUEST']._serialized_end=347
+  _globals['_INGESTIONRESPONSE']._serialized_start=349
+  _globals['_INGESTIONRESPONSE']._serialized_end=402
+  _globals['_GEODATA']._serialized_start=405
+  _globals['_GEODATA']._serialized_end=589
+  _globals['_FRAUDSIGNALS']._serialized_start=592
+  _globals['_FRAUDSIGNALS']._serialized_end=751
+  _globals['_ANONYMITYSIGNALS']._serialized_start=753
+  _globals['_ANONYMITYSIGNALS']._serialized_end=880
+  _globals['_BALANCESIGNALS']._serialized_start=883
+  _globals['_BALANCESIGNALS']._serialized_end=1033
+  _globals['_AGGREGATESIGNALS']._serialized_start=1035
+  _globals['_AGGREGATESIGNALS']._serialized_end=1155
+  _globals['_USERSIGNALS']._serialized_start=1157
+  _globals['_USERSIGNALS']._serialized_end=1215
+  _globals['_CARDTESTINGSIGNALS']._serialized_start=1218
+  _globals['_CARDTESTINGSIGNALS']._serialized_end=1399
+  _globals['_STRUCTURINGSIGNALS']._serialized_start=1402
+  _globals['_STRUCTURINGSIGNALS']._serialized_end=1551
+  _globals['_REASONCONDITION']._serialized_start=1554
+  _globals['_REASONCONDITION']._serialized_end=1684
+  _globals['_RULEHIT']._serialized_start=1687
+  _globals['_RULEHIT']._serialized_end=1832
+  _globals['_REASON']._serialized_start=1834
+  _globals['_REASON']._serialized_end=1957
+  _globals['_ENRICHEDTRANSACTION']._serialized_start=1960
+  _globals['_ENRICHEDTRANSACTION']._serialized_end=3211
+  _globals['_ENRICHEDTRANSACTION_CHALLENGERSCORESENTRY']._serialized_start=3097
+  _globals['_ENRICHEDTRANSACTION_CHALLENGERSCORESENTRY']._serialized_end=3152
+  _globals['_ENRICHEDTRANSACTION_MODELCONTRIBUTIONSENTRY']._serialized_start=3154
+  _globals['_ENRICHEDTRANSACTION_MODELCONTRIBUTIONSENTRY']._serialized_end=3211
+  _globals['_DECISION']._serialized_start=3214
+  _globals['_DECISION']._serialized_end=3490
+  _globals['_ALERT']._serialized_start=3493
+  _globals['_ALERT']._serialized_end=4008
+  _globals['_ALERT_NUMERICFEATURESENTRY']._serialized_start=3894
+  _globals['_ALERT_NUMERICFEATURESENTRY']._serialized_end=3948
+  _globals['_ALERT_CATEGORICALFEATURESENTRY']._serialized_start=3950
+  _globals['_ALERT_CATEGORICALFEATURESENTRY']._serialized_end=4008
+  _globals['_FRAUDINGESTION']._serialized_start=4010
+  _globals['_FRAUDINGESTION']._serialized_end=4098
 # @@protoc_insertion_point(module_scope)
@@ -50,6 +50,11 @@ else:
 
 USER_IDS = USER_IDS[:USERIDS_LIMIT]
 
+# Destination accounts in PaySim's nameDest format (C = customer, M = merchant).
+# The SDV model doesn't generate nameDest, so fraud transfers go to a small
+# shared pool of mule accounts, as many users paying into one destination.
+MULE_ACCOUNTS = [f"C{random.randint(10**8, 10**9 - 1)}" for _ in range(5)]
+
 # Mesa Agent - A single banking customer
 class BankingAgent(Agent):
     def __init__(self, model, unique_id):
@@ -63,6 +68,8 @@ class BankingAgent(Agent):
             f"{self.home_ip.split('.')[0]}.{random.randint(10,99)}.{random.randint(0,255)}.{random.randint(1,254)}"
             for _ in range(3)
         ]
+        self.payees = [f"C{random.randint(10**8, 10**9 - 1)}" for _ in range(3)]
+        self.merchants = [f"M{random.randint(10**8, 10**9 - 1)}" for _ in range(5)]
 
     # Decides the IP address for a specific transaction based on fraud status.
     def generate_context_ip(self, is_fraud):
@@ -86,6 +93,18 @@ class BankingAgent(Agent):
         else:
             return self.fake.ipv4_public() # Completely random
 
+    # Decides the receiving account: the sample's own nameDest when the model
+    # has one, a mule for fraudulent transfers, otherwise a regular payee
+    def generate_dest_account(self, sample, is_fraud):
+        if 'nameDest' in sample:
+            return str(sample['nameDest'])
+        txn_type = str(sample['type'])
+        if is_fraud and txn_type in ('TRANSFER', 'CASH_OUT'):
+            return random.choice(MULE_ACCOUNTS)
+        if txn_type == 'PAYMENT':
+            return random.choice(self.merchants)
+        return random.choice(self.payees)
+
 # Mesa Model
 class FraudSimulationModel(Model):
 
@@ -134,10 +153,12 @@ class FraudSimulationModel(Model):
         # Context IP generation
         ip_address = agent.generate_context_ip(is_fraud)
 
+        dest_account = agent.generate_dest_account(sample, is_fraud)
+
         # Send
-        self._send_transaction(agent.unique_id, sample, ip_address, is_fraud)
+        self._send_transaction(agent.unique_id, sample, ip_address, dest_account, is_fraud)
     
-    def _send_transaction(self, user_id, sample, ip_address, is_fraud):
+    def _send_transaction(self, user_id, sample, ip_address, dest_account, is_fraud):
         try:
             req = fraud_pb2.TransactionRequest(
                     transaction_id = str(uuid.uuid4()),
@@ -151,12 +172,13 @@ class FraudSimulationModel(Model):
                     old_balance_dest = float(sample['oldBalanceDest']),
                     new_balance_dest = float(sample['newBalanceDest']),
                     is_unauthorized_overdraft = float(sample['isUnauthorizedOverdraft']),
-                    ip_address = ip_address
+                    ip_address = ip_address,
+                    dest_account = dest_account
                 )
 
             self.grpc_stub.SendTransaction(req)
 
-            logger.info(f"Sent: User {user_id} | Amt {req.amount:.2f} | IP: {ip_address} | Fraud: {is_fraud}")
+            logger.info(f"Sent: User {user_id} | Amt {req.amount:.2f} | IP: {ip_address} | Dest: {dest_account} | Fraud: {is_fraud}")
     
         except grpc.RpcError as e:
             print(f"gRPC Error: {e.code()} - {e.details()}")